@@ -1,6 +1,17 @@
 package wx
 
-import "encoding/xml"
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+)
+
+// indexedDecoder is implemented by responses holding fields suffixed with
+// an index, such as coupon_id_$n, which encoding/xml cannot decode.
+type indexedDecoder interface {
+	decodeIndexed(values map[string]string)
+}
 
 // Payment returns to App.
 type Payment struct {
@@ -17,16 +28,30 @@ type unifiedOrderReq struct {
 	XMLName        xml.Name `xml:"xml"`
 	AppID          string   `xml:"appid"`            // 应用ID
 	MchID          string   `xml:"mch_id"`           // 商户号
+	SubAppID       string   `xml:"sub_appid"`        // 子商户公众账号ID
+	SubMchID       string   `xml:"sub_mch_id"`       // 子商户号
+	DeviceInfo     string   `xml:"device_info"`      // 设备号
 	NonceStr       string   `xml:"nonce_str"`        // 随机字符串
+	SignType       string   `xml:"sign_type"`        // 签名类型
 	Body           string   `xml:"body"`             // 商品描述
+	Detail         string   `xml:"detail"`           // 商品详情
 	Attach         string   `xml:"attach"`           // 附加数据
 	OutTradeNo     string   `xml:"out_trade_no"`     // 商户订单号
+	FeeType        string   `xml:"fee_type"`         // 标价币种
 	TotalFee       string   `xml:"total_fee"`        // 总金额
 	SpbillCreateIP string   `xml:"spbill_create_ip"` // 终端IP
-	NotifyURL      string   `xml:"notify_url"`       // 通知地址
-	TradeType      string   `xml:"trade_type"`       // 交易类型
 	TimeStart      string   `xml:"time_start"`       // 交易起始时间
 	TimeExpire     string   `xml:"time_expire"`      // 交易结束时间
+	GoodsTag       string   `xml:"goods_tag"`        // 订单优惠标记
+	NotifyURL      string   `xml:"notify_url"`       // 通知地址
+	TradeType      string   `xml:"trade_type"`       // 交易类型
+	ProductID      string   `xml:"product_id"`       // 商品ID
+	LimitPay       string   `xml:"limit_pay"`        // 指定支付方式
+	OpenID         string   `xml:"openid"`           // 用户标识
+	SubOpenID      string   `xml:"sub_openid"`       // 用户子标识
+	Receipt        string   `xml:"receipt"`          // 电子发票入口开放标识
+	ProfitSharing  string   `xml:"profit_sharing"`   // 是否需要分账
+	SceneInfo      string   `xml:"scene_info"`       // 场景信息
 }
 
 func (req unifiedOrderReq) URI() string {
@@ -44,6 +69,8 @@ type UnifiedOrderRsp struct {
 	ReturnMsg   string   `xml:"return_msg"`   // 返回信息
 	AppID       string   `xml:"appid"`        // 应用APPID
 	MchID       string   `xml:"mch_id"`       // 商户号
+	SubAppID    string   `xml:"sub_appid"`    // 子商户公众账号ID
+	SubMchID    string   `xml:"sub_mch_id"`   // 子商户号
 	DeviceInfo  string   `xml:"device_info"`  // 设备号
 	NonceStr    string   `xml:"nonce_str"`    // 随机字符串
 	Sign        string   `xml:"sign"`         // 签名
@@ -52,15 +79,60 @@ type UnifiedOrderRsp struct {
 	ErrCodeDesc string   `xml:"err_code_des"` // 错误代码描述
 	TradeType   string   `xml:"trade_type"`   // 交易类型
 	PrepayID    string   `xml:"prepay_id"`    // 预支付交易会话标识
+	CodeURL     string   `xml:"code_url"`     // 二维码链接
+	MwebURL     string   `xml:"mweb_url"`     // 支付跳转链接
+}
+
+// OrderDetail is the detail of goods for 单品优惠.
+type OrderDetail struct {
+	CostPrice   int          `json:"cost_price,omitempty"` // 订单原价
+	ReceiptID   string       `json:"receipt_id,omitempty"` // 商品小票ID
+	GoodsDetail []OrderGoods `json:"goods_detail"`         // 单品列表
+}
+
+// OrderGoods is a goods of the order.
+type OrderGoods struct {
+	GoodsID      string `json:"goods_id"`                 // 商品编码
+	WxpayGoodsID string `json:"wxpay_goods_id,omitempty"` // 微信侧商品编码
+	GoodsName    string `json:"goods_name,omitempty"`     // 商品名称
+	Quantity     int    `json:"quantity"`                 // 商品数量
+	Price        int    `json:"price"`                    // 商品单价
+}
+
+// SceneInfo describes where the order is created.
+type SceneInfo struct {
+	StoreInfo *StoreInfo `json:"store_info,omitempty"` // 门店信息
+	H5Info    *H5Info    `json:"h5_info,omitempty"`    // H5支付场景信息
+}
+
+// StoreInfo is the store where the order is created.
+type StoreInfo struct {
+	ID       string `json:"id"`                  // 门店id
+	Name     string `json:"name,omitempty"`      // 门店名称
+	AreaCode string `json:"area_code,omitempty"` // 门店行政区划码
+	Address  string `json:"address,omitempty"`   // 门店详细地址
+}
+
+// H5Info is the website or App paying with MWEB.
+type H5Info struct {
+	Type        string `json:"type"`                   // 场景类型，IOS、Android或Wap
+	AppName     string `json:"app_name,omitempty"`     // 应用名
+	BundleID    string `json:"bundle_id,omitempty"`    // IOS bundle_id
+	PackageName string `json:"package_name,omitempty"` // Android包名
+	WapURL      string `json:"wap_url,omitempty"`      // WAP网站URL地址
+	WapName     string `json:"wap_name,omitempty"`     // WAP网站名
 }
 
 type queryOrderReq struct {
 	XMLName       xml.Name `xml:"xml"`
 	AppID         string   `xml:"appid"`          // 应用APPID
 	MchID         string   `xml:"mch_id"`         // 商户号
+	SubAppID      string   `xml:"sub_appid"`      // 子商户公众账号ID
+	SubMchID      string   `xml:"sub_mch_id"`     // 子商户号
 	TransactionID string   `xml:"transaction_id"` // 微信订单号
 	OutTradeNo    string   `xml:"out_trade_no"`   // 商户订单号
 	NonceStr      string   `xml:"nonce_str"`      // 随机字符串
+	SignType      string   `xml:"sign_type"`      // 签名类型
 }
 
 func (req queryOrderReq) URI() string {
@@ -73,45 +145,125 @@ func (req queryOrderReq) SandBoxURI() string {
 
 // QueryOrderRsp is the response returned by /pay/orderquery
 type QueryOrderRsp struct {
-	XMLName        xml.Name `xml:"xml"`
-	ReturnCode     string   `xml:"return_code"`      // 返回状态码
-	ReturnMsg      string   `xml:"return_msg"`       // 返回信息
-	AppID          string   `xml:"appid"`            // 应用APPID
-	MchID          string   `xml:"mch_id"`           // 商户号
-	NonceStr       string   `xml:"nonce_str"`        // 随机字符串
-	Sign           string   `xml:"sign"`             // 签名
-	ResultCode     string   `xml:"result_code"`      // 业务结果
-	ErrCode        string   `xml:"err_code"`         // 错误代码
-	ErrCodeDesc    string   `xml:"err_code_des"`     // 错误代码描述
-	DeviceInfo     string   `xml:"device_info"`      // 设备号
-	OpenID         string   `xml:"openid"`           // 用户标识
-	IsSubscribe    string   `xml:"is_subscribe"`     // 是否关注公众账号
-	TradeType      string   `xml:"trade_type"`       // 交易类型
-	TradeState     string   `xml:"trade_state"`      // 交易状态
-	BankType       string   `xml:"bank_type"`        // 付款银行
-	TotalFee       string   `xml:"total_fee"`        // 总金额
-	FeeType        string   `xml:"fee_type"`         // 货币种类
-	CashFee        string   `xml:"cash_fee"`         // 现金支付金额
-	CashFeeType    string   `xml:"cash_fee_type"`    // 现金支付货币类型
-	CouponFee      string   `xml:"coupon_fee"`       // 代金券或立减优惠金额
-	CouponCount    string   `xml:"coupon_count"`     // 代金券或立减优惠使用数量
-	TransactionID  string   `xml:"transaction_id"`   // 微信支付订单号
-	OutTradeNo     string   `xml:"out_trade_no"`     // 商户订单号
-	Attach         string   `xml:"attach"`           // 附加数据
-	TimeEnd        string   `xml:"time_end"`         // 支付完成时间
-	TradeStateDesc string   `xml:"trade_state_desc"` // 交易状态描述
+	XMLName            xml.Name `xml:"xml"`
+	ReturnCode         string   `xml:"return_code"`          // 返回状态码
+	ReturnMsg          string   `xml:"return_msg"`           // 返回信息
+	AppID              string   `xml:"appid"`                // 应用APPID
+	MchID              string   `xml:"mch_id"`               // 商户号
+	SubAppID           string   `xml:"sub_appid"`            // 子商户公众账号ID
+	SubMchID           string   `xml:"sub_mch_id"`           // 子商户号
+	NonceStr           string   `xml:"nonce_str"`            // 随机字符串
+	Sign               string   `xml:"sign"`                 // 签名
+	ResultCode         string   `xml:"result_code"`          // 业务结果
+	ErrCode            string   `xml:"err_code"`             // 错误代码
+	ErrCodeDesc        string   `xml:"err_code_des"`         // 错误代码描述
+	DeviceInfo         string   `xml:"device_info"`          // 设备号
+	OpenID             string   `xml:"openid"`               // 用户标识
+	IsSubscribe        string   `xml:"is_subscribe"`         // 是否关注公众账号
+	SubOpenID          string   `xml:"sub_openid"`           // 用户子标识
+	SubIsSubscribe     string   `xml:"sub_is_subscribe"`     // 是否关注子公众账号
+	TradeType          string   `xml:"trade_type"`           // 交易类型
+	TradeState         string   `xml:"trade_state"`          // 交易状态
+	BankType           string   `xml:"bank_type"`            // 付款银行
+	TotalFee           string   `xml:"total_fee"`            // 总金额
+	FeeType            string   `xml:"fee_type"`             // 货币种类
+	CashFee            string   `xml:"cash_fee"`             // 现金支付金额
+	CashFeeType        string   `xml:"cash_fee_type"`        // 现金支付货币类型
+	CouponFee          string   `xml:"coupon_fee"`           // 代金券或立减优惠金额
+	CouponCount        string   `xml:"coupon_count"`         // 代金券或立减优惠使用数量
+	TransactionID      string   `xml:"transaction_id"`       // 微信支付订单号
+	OutTradeNo         string   `xml:"out_trade_no"`         // 商户订单号
+	Attach             string   `xml:"attach"`               // 附加数据
+	TimeEnd            string   `xml:"time_end"`             // 支付完成时间
+	TradeStateDesc     string   `xml:"trade_state_desc"`     // 交易状态描述
+	SettlementTotalFee string   `xml:"settlement_total_fee"` // 应结订单金额
+	PromotionDetail    string   `xml:"promotion_detail"`     // 营销详情
+	Coupons            []Coupon `xml:"-"`                    // 代金券
+}
+
+func (rsp *QueryOrderRsp) decodeIndexed(values map[string]string) {
+	rsp.Coupons = decodeCoupons(values)
+}
+
+// Promotions returns the promotions decoded from PromotionDetail.
+func (rsp *QueryOrderRsp) Promotions() ([]Promotion, error) {
+	return decodePromotionDetail(rsp.PromotionDetail)
+}
+
+// Coupon is a coupon used in payment.
+type Coupon struct {
+	CouponType string // 代金券类型
+	CouponID   string // 代金券ID
+	CouponFee  string // 单个代金券支付金额
+}
+
+func decodeCoupons(values map[string]string) []Coupon {
+	var coupons []Coupon
+	count, _ := strconv.Atoi(values["coupon_count"])
+	for i := 0; i < count; i++ {
+		coupons = append(coupons, Coupon{
+			CouponType: values[fmt.Sprintf("coupon_type_%d", i)],
+			CouponID:   values[fmt.Sprintf("coupon_id_%d", i)],
+			CouponFee:  values[fmt.Sprintf("coupon_fee_%d", i)],
+		})
+	}
+	return coupons
+}
+
+// Promotion is a promotion of 单品优惠.
+type Promotion struct {
+	PromotionID        string           `json:"promotion_id"`        // 券ID
+	Name               string           `json:"name"`                // 优惠名称
+	Scope              string           `json:"scope"`               // 优惠范围
+	Type               string           `json:"type"`                // 优惠类型
+	Amount             int              `json:"amount"`              // 优惠券面额
+	ActivityID         string           `json:"activity_id"`         // 活动ID
+	WxpayContribute    int              `json:"wxpay_contribute"`    // 微信出资
+	MerchantContribute int              `json:"merchant_contribute"` // 商户出资
+	OtherContribute    int              `json:"other_contribute"`    // 其他出资
+	GoodsDetail        []PromotionGoods `json:"goods_detail"`        // 单品列表
+}
+
+// PromotionGoods is a goods the promotion applies to.
+type PromotionGoods struct {
+	GoodsID        string `json:"goods_id"`        // 商品编码
+	GoodsRemark    string `json:"goods_remark"`    // 商品备注
+	Quantity       int    `json:"quantity"`        // 商品数量
+	Price          int    `json:"price"`           // 商品价格
+	DiscountAmount int    `json:"discount_amount"` // 商品优惠金额
+}
+
+func decodePromotionDetail(detail string) ([]Promotion, error) {
+	if detail == "" {
+		return nil, nil
+	}
+
+	var result struct {
+		PromotionDetail []Promotion `json:"promotion_detail"`
+	}
+	if err := json.Unmarshal([]byte(detail), &result); err != nil {
+		return nil, err
+	}
+	return result.PromotionDetail, nil
 }
 
 type refundOrderReq struct {
 	XMLName       xml.Name `xml:"xml"`
-	AppID         string   `xml:"appid"`          // 应用ID
-	MchID         string   `xml:"mch_id"`         // 商户号
-	NonceStr      string   `xml:"nonce_str"`      // 随机字符串
-	TransactionID string   `xml:"transaction_id"` // 微信订单号
-	OutTradeNo    string   `xml:"out_trade_no"`   // 商户订单号
-	OutRefundNo   string   `xml:"out_refund_no"`  // 商户退款单号
-	TotalFee      string   `xml:"total_fee"`      // 总金额
-	RefundFee     string   `xml:"refund_fee"`     // 退款金额
+	AppID         string   `xml:"appid"`           // 应用ID
+	MchID         string   `xml:"mch_id"`          // 商户号
+	SubAppID      string   `xml:"sub_appid"`       // 子商户公众账号ID
+	SubMchID      string   `xml:"sub_mch_id"`      // 子商户号
+	NonceStr      string   `xml:"nonce_str"`       // 随机字符串
+	SignType      string   `xml:"sign_type"`       // 签名类型
+	TransactionID string   `xml:"transaction_id"`  // 微信订单号
+	OutTradeNo    string   `xml:"out_trade_no"`    // 商户订单号
+	OutRefundNo   string   `xml:"out_refund_no"`   // 商户退款单号
+	TotalFee      string   `xml:"total_fee"`       // 总金额
+	RefundFee     string   `xml:"refund_fee"`      // 退款金额
+	RefundFeeType string   `xml:"refund_fee_type"` // 退款货币种类
+	RefundDesc    string   `xml:"refund_desc"`     // 退款原因
+	RefundAccount string   `xml:"refund_account"`  // 退款资金来源
+	NotifyURL     string   `xml:"notify_url"`      // 退款结果通知url
 }
 
 func (req refundOrderReq) URI() string {
@@ -122,34 +274,76 @@ func (req refundOrderReq) SandBoxURI() string {
 	return "https://api.mch.weixin.qq.com/sandboxnew/secapi/pay/refund"
 }
 
+// RefundOrderRsp is the response returned by /secapi/pay/refund.
 type RefundOrderRsp struct {
-	XMLName       xml.Name `xml:"xml"`
-	ReturnCode    string   `xml:"return_code"`    // 返回状态码
-	ReturnMsg     string   `xml:"return_msg"`     // 返回信息
-	ResultCode    string   `xml:"result_code"`    // 业务结果
-	ErrCode       string   `xml:"err_code"`       // 错误代码
-	ErrCodeDesc   string   `xml:"err_code_des"`   // 错误代码描述
-	AppID         string   `xml:"appid"`          // 应用APPID
-	MchID         string   `xml:"mch_id"`         // 商户号
-	NonceStr      string   `xml:"nonce_str"`      // 随机字符串
-	TransactionID string   `xml:"transaction_id"` // 微信订单号
-	OutTradeNo    string   `xml:"out_trade_no"`   // 商户订单号
-	OutRefundNo   string   `xml:"out_refund_no"`  // 商户退款单号
-	RefundID      string   `xml:"refund_id"`      // 微信退款单号
-	RefundFee     string   `xml:"refund_fee"`     // 退款金额
-	TotalFee      string   `xml:"total_fee"`      // 标价金额
-	CashFee       string   `xml:"cash_fee"`       // 现金支付金额
+	XMLName             xml.Name       `xml:"xml"`
+	ReturnCode          string         `xml:"return_code"`           // 返回状态码
+	ReturnMsg           string         `xml:"return_msg"`            // 返回信息
+	ResultCode          string         `xml:"result_code"`           // 业务结果
+	ErrCode             string         `xml:"err_code"`              // 错误代码
+	ErrCodeDesc         string         `xml:"err_code_des"`          // 错误代码描述
+	AppID               string         `xml:"appid"`                 // 应用APPID
+	MchID               string         `xml:"mch_id"`                // 商户号
+	SubAppID            string         `xml:"sub_appid"`             // 子商户公众账号ID
+	SubMchID            string         `xml:"sub_mch_id"`            // 子商户号
+	NonceStr            string         `xml:"nonce_str"`             // 随机字符串
+	Sign                string         `xml:"sign"`                  // 签名
+	TransactionID       string         `xml:"transaction_id"`        // 微信订单号
+	OutTradeNo          string         `xml:"out_trade_no"`          // 商户订单号
+	OutRefundNo         string         `xml:"out_refund_no"`         // 商户退款单号
+	RefundID            string         `xml:"refund_id"`             // 微信退款单号
+	RefundFee           string         `xml:"refund_fee"`            // 退款金额
+	SettlementRefundFee string         `xml:"settlement_refund_fee"` // 应结退款金额
+	TotalFee            string         `xml:"total_fee"`             // 标价金额
+	SettlementTotalFee  string         `xml:"settlement_total_fee"`  // 应结订单金额
+	FeeType             string         `xml:"fee_type"`              // 标价币种
+	CashFee             string         `xml:"cash_fee"`              // 现金支付金额
+	CashFeeType         string         `xml:"cash_fee_type"`         // 现金支付币种
+	CashRefundFee       string         `xml:"cash_refund_fee"`       // 现金退款金额
+	CouponRefundFee     string         `xml:"coupon_refund_fee"`     // 代金券退款总金额
+	CouponRefundCount   string         `xml:"coupon_refund_count"`   // 退款代金券使用数量
+	Coupons             []RefundCoupon `xml:"-"`                     // 退款代金券
+}
+
+func (rsp *RefundOrderRsp) decodeIndexed(values map[string]string) {
+	rsp.Coupons = decodeRefundCoupons(values, "")
+}
+
+// RefundCoupon is a coupon refunded.
+type RefundCoupon struct {
+	CouponType      string // 代金券类型
+	CouponRefundID  string // 退款代金券ID
+	CouponRefundFee string // 单个退款代金券支付金额
+}
+
+// decodeRefundCoupons decodes refund coupons with suffix, such as "_$n" for
+// refunds indexed by $n.
+func decodeRefundCoupons(values map[string]string, suffix string) []RefundCoupon {
+	var coupons []RefundCoupon
+	count, _ := strconv.Atoi(values["coupon_refund_count"+suffix])
+	for i := 0; i < count; i++ {
+		coupons = append(coupons, RefundCoupon{
+			CouponType:      values[fmt.Sprintf("coupon_type%s_%d", suffix, i)],
+			CouponRefundID:  values[fmt.Sprintf("coupon_refund_id%s_%d", suffix, i)],
+			CouponRefundFee: values[fmt.Sprintf("coupon_refund_fee%s_%d", suffix, i)],
+		})
+	}
+	return coupons
 }
 
 type queryRefundReq struct {
 	XMLName       xml.Name `xml:"xml"`
 	AppID         string   `xml:"appid"`          // 应用ID
 	MchID         string   `xml:"mch_id"`         // 商户号
+	SubAppID      string   `xml:"sub_appid"`      // 子商户公众账号ID
+	SubMchID      string   `xml:"sub_mch_id"`     // 子商户号
 	NonceStr      string   `xml:"nonce_str"`      // 随机字符串
+	SignType      string   `xml:"sign_type"`      // 签名类型
 	TransactionID string   `xml:"transaction_id"` // 微信订单号
 	OutTradeNo    string   `xml:"out_trade_no"`   // 商户订单号
 	OutRefundNo   string   `xml:"out_refund_no"`  // 商户退款单号
 	RefundID      string   `xml:"refund_id"`      // 微信退款单号
+	Offset        string   `xml:"offset"`         // 偏移量
 }
 
 func (req queryRefundReq) URI() string {
@@ -160,21 +354,94 @@ func (req queryRefundReq) SandBoxURI() string {
 	return "https://api.mch.weixin.qq.com/sandboxnew/pay/refundquery"
 }
 
+// QueryRefundRsp is the response returned by /pay/refundquery.
 type QueryRefundRsp struct {
-	XMLName       xml.Name `xml:"xml"`
-	ReturnCode    string   `xml:"return_code"`    // 返回状态码
-	ReturnMsg     string   `xml:"return_msg"`     // 返回信息
-	ResultCode    string   `xml:"result_code"`    // 业务结果
-	ErrCode       string   `xml:"err_code"`       // 错误代码
-	ErrCodeDesc   string   `xml:"err_code_des"`   // 错误代码描述
-	AppID         string   `xml:"appid"`          // 应用APPID
-	MchID         string   `xml:"mch_id"`         // 商户号
-	NonceStr      string   `xml:"nonce_str"`      // 随机字符串
-	TransactionID string   `xml:"transaction_id"` // 微信订单号
-	OutTradeNo    string   `xml:"out_trade_no"`   // 商户订单号
-	TotalFee      string   `xml:"total_fee"`      // 标价金额
-	CashFee       string   `xml:"cash_fee"`       // 现金支付金额
-	RefundCount   string   `xml:"refund_count"`   // 退款笔数
+	XMLName            xml.Name `xml:"xml"`
+	ReturnCode         string   `xml:"return_code"`          // 返回状态码
+	ReturnMsg          string   `xml:"return_msg"`           // 返回信息
+	ResultCode         string   `xml:"result_code"`          // 业务结果
+	ErrCode            string   `xml:"err_code"`             // 错误代码
+	ErrCodeDesc        string   `xml:"err_code_des"`         // 错误代码描述
+	AppID              string   `xml:"appid"`                // 应用APPID
+	MchID              string   `xml:"mch_id"`               // 商户号
+	SubAppID           string   `xml:"sub_appid"`            // 子商户公众账号ID
+	SubMchID           string   `xml:"sub_mch_id"`           // 子商户号
+	NonceStr           string   `xml:"nonce_str"`            // 随机字符串
+	Sign               string   `xml:"sign"`                 // 签名
+	TotalRefundCount   string   `xml:"total_refund_count"`   // 订单总退款次数
+	TransactionID      string   `xml:"transaction_id"`       // 微信订单号
+	OutTradeNo         string   `xml:"out_trade_no"`         // 商户订单号
+	TotalFee           string   `xml:"total_fee"`            // 标价金额
+	SettlementTotalFee string   `xml:"settlement_total_fee"` // 应结订单金额
+	FeeType            string   `xml:"fee_type"`             // 标价币种
+	CashFee            string   `xml:"cash_fee"`             // 现金支付金额
+	RefundCount        string   `xml:"refund_count"`         // 退款笔数
+	Refunds            []Refund `xml:"-"`                    // 退款记录
+}
+
+func (rsp *QueryRefundRsp) decodeIndexed(values map[string]string) {
+	count, _ := strconv.Atoi(values["refund_count"])
+	for i := 0; i < count; i++ {
+		suffix := fmt.Sprintf("_%d", i)
+		rsp.Refunds = append(rsp.Refunds, Refund{
+			OutRefundNo:         values["out_refund_no"+suffix],
+			RefundID:            values["refund_id"+suffix],
+			RefundChannel:       values["refund_channel"+suffix],
+			RefundFee:           values["refund_fee"+suffix],
+			SettlementRefundFee: values["settlement_refund_fee"+suffix],
+			CouponRefundFee:     values["coupon_refund_fee"+suffix],
+			CouponRefundCount:   values["coupon_refund_count"+suffix],
+			Coupons:             decodeRefundCoupons(values, suffix),
+			RefundStatus:        values["refund_status"+suffix],
+			RefundAccount:       values["refund_account"+suffix],
+			RefundRecvAccout:    values["refund_recv_accout"+suffix],
+			RefundSuccessTime:   values["refund_success_time"+suffix],
+		})
+	}
+}
+
+// Refund is a refund of the order queried.
+type Refund struct {
+	OutRefundNo         string         // 商户退款单号
+	RefundID            string         // 微信退款单号
+	RefundChannel       string         // 退款渠道
+	RefundFee           string         // 申请退款金额
+	SettlementRefundFee string         // 退款金额
+	CouponRefundFee     string         // 总代金券退款金额
+	CouponRefundCount   string         // 退款代金券使用数量
+	Coupons             []RefundCoupon // 退款代金券
+	RefundStatus        string         // 退款状态
+	RefundAccount       string         // 退款资金来源
+	RefundRecvAccout    string         // 退款入账账户
+	RefundSuccessTime   string         // 退款成功时间
+}
+
+type downloadFundFlowReq struct {
+	XMLName     xml.Name `xml:"xml"`
+	AppID       string   `xml:"appid"`        // 应用ID
+	MchID       string   `xml:"mch_id"`       // 商户号
+	SubAppID    string   `xml:"sub_appid"`    // 子商户公众账号ID
+	SubMchID    string   `xml:"sub_mch_id"`   // 子商户号
+	NonceStr    string   `xml:"nonce_str"`    // 随机字符串
+	SignType    string   `xml:"sign_type"`    // 签名类型，只支持HMAC-SHA256
+	BillDate    string   `xml:"bill_date"`    // 资金账单日期
+	AccountType string   `xml:"account_type"` // 资金账户类型
+}
+
+func (req downloadFundFlowReq) URI() string {
+	return "https://api.mch.weixin.qq.com/pay/downloadfundflow"
+}
+
+func (req downloadFundFlowReq) SandBoxURI() string {
+	return "https://api.mch.weixin.qq.com/sandboxnew/pay/downloadfundflow"
+}
+
+// downloadBillErrRsp is returned instead of the bill when downloading fails.
+type downloadBillErrRsp struct {
+	XMLName    xml.Name `xml:"xml"`
+	ReturnCode string   `xml:"return_code"` // 返回状态码
+	ReturnMsg  string   `xml:"return_msg"`  // 返回信息
+	ErrCode    string   `xml:"error_code"`  // 错误码
 }
 
 type getSandBoxSignKeyReq struct {
@@ -0,0 +1,268 @@
+package wx
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// FundFlowBill is the bill returned by /pay/downloadfundflow.
+type FundFlowBill struct {
+	Records []FundFlowRecord
+	Summary FundFlowSummary
+}
+
+// FundFlowRecord is a single fund flow of the bill.
+type FundFlowRecord struct {
+	BillingTime   string // 记账时间
+	TransactionID string // 微信支付业务单号
+	FundFlowID    string // 资金流水单号
+	BizName       string // 业务名称
+	BizType       string // 业务类型
+	FinancialType string // 收支类型
+	Amount        string // 收支金额（元）
+	Balance       string // 账户结余（元）
+	Applicant     string // 资金变更提交申请人
+	Remark        string // 备注
+	BizVoucherID  string // 业务凭证号
+}
+
+// FundFlowSummary is the summary at the end of the bill.
+type FundFlowSummary struct {
+	TotalCount        string // 资金流水总笔数
+	IncomeCount       string // 收入笔数
+	IncomeAmount      string // 收入金额
+	ExpenditureCount  string // 支出笔数
+	ExpenditureAmount string // 支出金额
+}
+
+func parseFundFlowBill(data []byte) (*FundFlowBill, error) {
+	rows, summary, err := readBill(data)
+	if err != nil {
+		return nil, err
+	}
+
+	bill := &FundFlowBill{}
+	for _, row := range rows {
+		bill.Records = append(bill.Records, FundFlowRecord{
+			BillingTime:   column(row, 0),
+			TransactionID: column(row, 1),
+			FundFlowID:    column(row, 2),
+			BizName:       column(row, 3),
+			BizType:       column(row, 4),
+			FinancialType: column(row, 5),
+			Amount:        column(row, 6),
+			Balance:       column(row, 7),
+			Applicant:     column(row, 8),
+			Remark:        column(row, 9),
+			BizVoucherID:  column(row, 10),
+		})
+	}
+
+	if len(summary) > 0 {
+		bill.Summary = FundFlowSummary{
+			TotalCount:        column(summary[0], 0),
+			IncomeCount:       column(summary[0], 1),
+			IncomeAmount:      column(summary[0], 2),
+			ExpenditureCount:  column(summary[0], 3),
+			ExpenditureAmount: column(summary[0], 4),
+		}
+	}
+
+	return bill, nil
+}
+
+// TradeBill is the trade bill of all orders, successful orders or refunds.
+// Columns absent from the bill type are left empty.
+type TradeBill struct {
+	Records []TradeRecord
+	Summary TradeSummary
+}
+
+// TradeRecord is a single trade of the bill.
+type TradeRecord struct {
+	TradeTime          string // 交易时间
+	AppID              string // 公众账号ID
+	MchID              string // 商户号
+	SubMchID           string // 特约商户号
+	DeviceInfo         string // 设备号
+	TransactionID      string // 微信订单号
+	OutTradeNo         string // 商户订单号
+	OpenID             string // 用户标识
+	TradeType          string // 交易类型
+	TradeState         string // 交易状态
+	BankType           string // 付款银行
+	FeeType            string // 货币种类
+	SettlementTotalFee string // 应结订单金额
+	CouponFee          string // 代金券金额
+	RefundApplyTime    string // 退款申请时间，仅退款账单
+	RefundSuccessTime  string // 退款成功时间，仅退款账单
+	RefundID           string // 微信退款单号
+	OutRefundNo        string // 商户退款单号
+	RefundFee          string // 退款金额
+	CouponRefundFee    string // 充值券退款金额
+	RefundType         string // 退款类型
+	RefundStatus       string // 退款状态
+	Body               string // 商品名称
+	Attach             string // 商户数据包
+	Fee                string // 手续费
+	Rate               string // 费率
+	TotalFee           string // 订单金额
+	ApplyRefundFee     string // 申请退款金额
+	RateRemark         string // 费率备注
+}
+
+// TradeSummary is the summary at the end of the bill.
+type TradeSummary struct {
+	TotalCount         string // 总交易单数
+	SettlementTotalFee string // 应结订单总金额
+	RefundFee          string // 退款总金额
+	CouponRefundFee    string // 充值券退款总金额
+	Fee                string // 手续费总金额
+	TotalFee           string // 订单总金额
+	ApplyRefundFee     string // 申请退款总金额
+}
+
+// fields returns the members of record by the column names in the bill.
+func (record *TradeRecord) fields() map[string]*string {
+	return map[string]*string{
+		"交易时间":    &record.TradeTime,
+		"公众账号ID":  &record.AppID,
+		"商户号":     &record.MchID,
+		"特约商户号":   &record.SubMchID,
+		"设备号":     &record.DeviceInfo,
+		"微信订单号":   &record.TransactionID,
+		"商户订单号":   &record.OutTradeNo,
+		"用户标识":    &record.OpenID,
+		"交易类型":    &record.TradeType,
+		"交易状态":    &record.TradeState,
+		"付款银行":    &record.BankType,
+		"货币种类":    &record.FeeType,
+		"应结订单金额":  &record.SettlementTotalFee,
+		"代金券金额":   &record.CouponFee,
+		"退款申请时间":  &record.RefundApplyTime,
+		"退款成功时间":  &record.RefundSuccessTime,
+		"微信退款单号":  &record.RefundID,
+		"商户退款单号":  &record.OutRefundNo,
+		"退款金额":    &record.RefundFee,
+		"充值券退款金额": &record.CouponRefundFee,
+		"退款类型":    &record.RefundType,
+		"退款状态":    &record.RefundStatus,
+		"商品名称":    &record.Body,
+		"商户数据包":   &record.Attach,
+		"手续费":     &record.Fee,
+		"费率":      &record.Rate,
+		"订单金额":    &record.TotalFee,
+		"申请退款金额":  &record.ApplyRefundFee,
+		"费率备注":    &record.RateRemark,
+	}
+}
+
+// fields returns the members of summary by the column names in the bill.
+func (summary *TradeSummary) fields() map[string]*string {
+	return map[string]*string{
+		"总交易单数":    &summary.TotalCount,
+		"应结订单总金额":  &summary.SettlementTotalFee,
+		"退款总金额":    &summary.RefundFee,
+		"充值券退款总金额": &summary.CouponRefundFee,
+		"手续费总金额":   &summary.Fee,
+		"订单总金额":    &summary.TotalFee,
+		"申请退款总金额":  &summary.ApplyRefundFee,
+	}
+}
+
+// parseTradeBill parses trade bills of every type by the column names, as
+// the columns differ between bills of ALL, SUCCESS and REFUND.
+func parseTradeBill(data []byte) (*TradeBill, error) {
+	headers, rows, summary, err := readBillWithHeaders(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(headers) == 0 {
+		return nil, errors.New("trade bill header missing")
+	}
+
+	bill := &TradeBill{}
+	for _, row := range rows {
+		record := TradeRecord{}
+		if err = setColumns(record.fields(), headers[0], row); err != nil {
+			return nil, err
+		}
+		bill.Records = append(bill.Records, record)
+	}
+
+	if len(summary) > 0 && len(headers) > 1 {
+		if err = setColumns(bill.Summary.fields(), headers[1], summary[0]); err != nil {
+			return nil, err
+		}
+	}
+
+	return bill, nil
+}
+
+// setColumns sets fields by the names in header with the values in record.
+// Unknown columns are ignored, as Weixin may add columns.
+func setColumns(fields map[string]*string, header, record []string) error {
+	found := false
+	for i, name := range header {
+		if f, ok := fields[name]; ok {
+			*f = column(record, i)
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("unknown bill header %v", header)
+	}
+	return nil
+}
+
+// readBill splits a bill into detail rows and summary rows. Every bill starts
+// with a header line followed by detail rows, then a second header line
+// followed by the summary. Values in the rows are prefixed with a backquote,
+// which is removed.
+func readBill(data []byte) (rows, summary [][]string, err error) {
+	_, rows, summary, err = readBillWithHeaders(data)
+	return rows, summary, err
+}
+
+// readBillWithHeaders is readBill returning the header lines as well.
+func readBillWithHeaders(data []byte) (headers, rows, summary [][]string, err error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	for _, record := range records {
+		if len(record) == 0 || !strings.HasPrefix(record[0], "`") {
+			for i := range record {
+				record[i] = strings.TrimSpace(record[i])
+			}
+			headers = append(headers, record)
+			continue
+		}
+
+		for i := range record {
+			record[i] = strings.TrimPrefix(strings.TrimSpace(record[i]), "`")
+		}
+
+		if len(headers) > 1 {
+			summary = append(summary, record)
+		} else {
+			rows = append(rows, record)
+		}
+	}
+	return headers, rows, summary, nil
+}
+
+func column(record []string, i int) string {
+	if i < len(record) {
+		return record[i]
+	}
+	return ""
+}
@@ -0,0 +1,35 @@
+package wx
+
+import "testing"
+
+func TestParseFundFlowBill(t *testing.T) {
+	data := "记账时间,微信支付业务单号,资金流水单号,业务名称,业务类型,收支类型,收支金额（元）,账户结余（元）,资金变更提交申请人,备注,业务凭证号\r\n" +
+		"`2018-02-01 04:21:23,`50000305742018020103387128253,`1900009231201802015884652186,`退款,`退款,`支出,`0.02,`0.17,`system,`缺货,`REF4200000068201801293084726067\r\n" +
+		"`2018-02-01 04:21:24,`4200000068201801293084726067,`1900009231201802015884652187,`交易,`交易,`收入,`0.19,`0.19,`system,`,`4200000068201801293084726067\r\n" +
+		"资金流水总笔数,收入笔数,收入金额,支出笔数,支出金额\r\n" +
+		"`2,`1,`0.19,`1,`0.02\r\n"
+
+	bill, err := parseFundFlowBill([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(bill.Records) != 2 {
+		t.Fatalf("returned: %d records, expected: 2", len(bill.Records))
+	}
+
+	first := bill.Records[0]
+	if first.FundFlowID != "1900009231201802015884652186" {
+		t.Errorf("returned: %s, expected: %s", first.FundFlowID, "1900009231201802015884652186")
+	}
+	if first.Remark != "缺货" {
+		t.Errorf("returned: %s, expected: %s", first.Remark, "缺货")
+	}
+	if first.BizVoucherID != "REF4200000068201801293084726067" {
+		t.Errorf("returned: %s, expected: %s", first.BizVoucherID, "REF4200000068201801293084726067")
+	}
+
+	if bill.Summary.TotalCount != "2" || bill.Summary.ExpenditureAmount != "0.02" {
+		t.Errorf("returned: %#v", bill.Summary)
+	}
+}
@@ -2,14 +2,22 @@ package wx
 
 import (
 	"bytes"
+	"crypto/rsa"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
 	"encoding/xml"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
+	"regexp"
+	"sync"
 	"time"
+
+	"golang.org/x/crypto/pkcs12"
 )
 
 // constants for response.
@@ -17,6 +25,51 @@ const (
 	Success = "SUCCESS"
 )
 
+// amounts in fen of the acceptance cases in sandbox, which must be passed to
+// go live.
+const (
+	SandBoxPaySuccess = 551 // 支付成功，查询订单
+	SandBoxPayRefund  = 552 // 支付成功，退款，查询退款
+)
+
+// constants for trade type.
+const (
+	JSAPI  = "JSAPI"  // 公众号支付、小程序支付
+	Native = "NATIVE" // 扫码支付
+	App    = "APP"    // APP支付
+	MWeb   = "MWEB"   // H5支付
+)
+
+// NoCredit limits users from paying with credit cards.
+const NoCredit = "no_credit"
+
+// constants for account type of fund flow.
+const (
+	AccountBasic     = "Basic"     // 基本账户
+	AccountOperation = "Operation" // 运营账户
+	AccountFees      = "Fees"      // 手续费账户
+)
+
+// constants for refund status.
+const (
+	RefundSuccess    = "SUCCESS"     // 退款成功
+	RefundChange     = "CHANGE"      // 退款异常
+	RefundClose      = "REFUNDCLOSE" // 退款关闭
+	RefundProcessing = "PROCESSING"  // 退款处理中
+)
+
+// constants for refund account.
+const (
+	RefundSourceUnsettledFunds = "REFUND_SOURCE_UNSETTLED_FUNDS" // 未结算资金退款
+	RefundSourceRechargeFunds  = "REFUND_SOURCE_RECHARGE_FUNDS"  // 可用余额退款
+)
+
+// constants for sign type.
+const (
+	MD5        = "MD5"
+	HMACSHA256 = "HMAC-SHA256"
+)
+
 // Config contains all configuration info.
 type Config struct {
 	AppID     string
@@ -24,13 +77,33 @@ type Config struct {
 	MchID     string
 	NotifyURL string
 	TradeType string
+	SignType  string // MD5 by default
 	SandBox   bool
+
+	// SubAppID and SubMchID identify the sub-merchant in service provider
+	// mode, the AppID and MchID are those of the service provider.
+	SubAppID string
+	SubMchID string
+
+	// ProfitSharing requests orders to be profit shared, the amount is
+	// frozen until shared or finished.
+	ProfitSharing bool
 }
 
 // Client handles all transactions.
 type Client struct {
 	config    Config
 	tlsClient http.Client
+	cache     *cache
+}
+
+// cache holds keys fetched from Weixin, shared by clients of sub-merchants.
+type cache struct {
+	mu     sync.Mutex
+	pubKey *rsa.PublicKey // RSA public key for bank card encryption
+
+	sandBoxMu  sync.Mutex
+	sandBoxKey string // sign key in sandbox mode
 }
 
 // NewClient returns a *Client ready to use.
@@ -42,117 +115,360 @@ func NewClient(cfg Config) *Client {
 	return &Client{
 		config:    cfg,
 		tlsClient: client,
+		cache:     &cache{},
 	}
 }
 
-// WithCertificate configures a *Client with certificates.
+// WithSubMerchant returns a copy of *Client calling APIs on behalf of the
+// sub-merchant in service provider mode, subAppID is optional.
+func (c *Client) WithSubMerchant(subAppID, subMchID string) *Client {
+	cfg := c.config
+	cfg.SubAppID = subAppID
+	cfg.SubMchID = subMchID
+	return &Client{
+		config:    cfg,
+		tlsClient: c.tlsClient,
+		cache:     c.cache,
+	}
+}
+
+// WithCertificate configures a *Client with certificates. The rootCAF is
+// optional, the system root CAs are used if it is empty.
 func (c *Client) WithCertificate(certF, keyF, rootCAF string) error {
 	cert, err := tls.LoadX509KeyPair(certF, keyF)
 	if err != nil {
 		return err
 	}
 
-	data, err := ioutil.ReadFile(rootCAF)
+	var pool *x509.CertPool
+	if rootCAF != "" {
+		data, err := ioutil.ReadFile(rootCAF)
+		if err != nil {
+			return err
+		}
+
+		pool = x509.NewCertPool()
+		ok := pool.AppendCertsFromPEM(data)
+		if !ok {
+			return errors.New("failed to parse root certificate")
+		}
+	}
+
+	c.setCertificate(cert, pool)
+	return nil
+}
+
+// WithPEMCertificate configures a *Client with apiclient_cert.pem and
+// apiclient_key.pem in memory.
+func (c *Client) WithPEMCertificate(certPEM, keyPEM []byte) error {
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
 	if err != nil {
 		return err
 	}
 
-	pool := x509.NewCertPool()
-	ok := pool.AppendCertsFromPEM(data)
-	if !ok {
-		return errors.New("failed to parse root certificate")
+	c.setCertificate(cert, nil)
+	return nil
+}
+
+// WithPKCS12Certificate configures a *Client with apiclient_cert.p12 in
+// memory, password is MchID if empty.
+func (c *Client) WithPKCS12Certificate(data []byte, password string) error {
+	if password == "" {
+		password = c.config.MchID
 	}
 
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{
-			Certificates: []tls.Certificate{cert},
-			RootCAs:      pool,
-		},
+	blocks, err := pkcs12.ToPEM(data, password)
+	if err != nil {
+		return err
 	}
-	client := http.Client{Transport: tr}
-	c.tlsClient = client
 
-	return nil
+	var certPEM, keyPEM []byte
+	for _, b := range blocks {
+		if b.Type == "CERTIFICATE" {
+			certPEM = append(certPEM, pem.EncodeToMemory(b)...)
+		} else {
+			keyPEM = append(keyPEM, pem.EncodeToMemory(b)...)
+		}
+	}
+
+	return c.WithPEMCertificate(certPEM, keyPEM)
+}
+
+// setCertificate sets the client certificate on a copy of the current
+// transport, keeping its other settings. The root CAs are kept if pool is nil.
+func (c *Client) setCertificate(cert tls.Certificate, pool *x509.CertPool) {
+	tr, ok := c.tlsClient.Transport.(*http.Transport)
+	if ok {
+		tr = tr.Clone()
+	} else {
+		tr = http.DefaultTransport.(*http.Transport).Clone()
+	}
+
+	if tr.TLSClientConfig == nil {
+		tr.TLSClientConfig = &tls.Config{}
+	}
+	tr.TLSClientConfig.Certificates = []tls.Certificate{cert}
+	if pool != nil {
+		tr.TLSClientConfig.RootCAs = pool
+	}
+
+	c.tlsClient.Transport = tr
 }
 
 // UnifiedOrder creates new order from Weixin.
 func (c *Client) UnifiedOrder(totalFee int, desc, orderID, clientIP string, times ...time.Time) (*UnifiedOrderRsp, error) {
-	req := unifiedOrderReq{
-		AppID:          c.config.AppID,
-		MchID:          c.config.MchID,
-		NonceStr:       generateNonceStr(),
+	opts := UnifiedOrderOptions{
 		Body:           desc,
 		Attach:         "optional",
 		OutTradeNo:     orderID,
-		TotalFee:       fmt.Sprintf("%d", totalFee),
+		TotalFee:       totalFee,
 		SpbillCreateIP: clientIP,
-		NotifyURL:      c.config.NotifyURL,
-		TradeType:      c.config.TradeType,
 	}
 
 	if len(times) >= 2 {
-		startYr, startMon, startDay := times[0].Date()
-		startHr := times[0].Hour()
-		startMin := times[0].Minute()
-		startSec := times[0].Second()
-		expireYr, expireMon, expireDay := times[1].Date()
-		expireHr := times[1].Hour()
-		expireMin := times[1].Minute()
-		expireSec := times[1].Second()
-		req.TimeStart = fmt.Sprintf("%d%02d%02d%02d%02d%02d", startYr, startMon, startDay, startHr, startMin, startSec)
-		req.TimeExpire = fmt.Sprintf("%d%02d%02d%02d%02d%02d", expireYr, expireMon, expireDay, expireHr, expireMin, expireSec)
+		opts.TimeStart = times[0]
+		opts.TimeExpire = times[1]
 	}
 
-	reqMap, err := toMap(req)
+	return c.UnifiedOrderWithOptions(opts)
+}
+
+// UnifiedOrderOptions contains all parameters of /pay/unifiedorder.
+type UnifiedOrderOptions struct {
+	DeviceInfo     string       // 设备号，可选
+	Body           string       // 商品描述
+	Detail         *OrderDetail // 商品详情，可选
+	Attach         string       // 附加数据，可选
+	OutTradeNo     string       // 商户订单号
+	FeeType        string       // 标价币种，可选
+	TotalFee       int          // 标价金额
+	SpbillCreateIP string       // 终端IP
+	TimeStart      time.Time    // 交易起始时间，可选
+	TimeExpire     time.Time    // 交易结束时间，可选
+	GoodsTag       string       // 订单优惠标记，可选
+	NotifyURL      string       // 通知地址，默认为Config.NotifyURL
+	TradeType      string       // 交易类型，默认为Config.TradeType
+	ProductID      string       // 商品ID，NATIVE时必填
+	LimitPay       string       // 指定支付方式，可选
+	OpenID         string       // 用户标识，JSAPI时与SubOpenID二选一
+	SubOpenID      string       // 用户子标识，服务商模式
+	Receipt        bool         // 开发票入口开放标识
+	ProfitSharing  bool         // 是否需要分账，默认为Config.ProfitSharing
+	SceneInfo      *SceneInfo   // 场景信息，H5时必填
+	SignType       string       // 签名类型，默认为Config.SignType
+}
+
+var outTradeNoPattern = regexp.MustCompile(`^[0-9A-Za-z_\-|*]{1,32}$`)
+
+func (opts UnifiedOrderOptions) validate() error {
+	if opts.Body == "" || len(opts.Body) > 128 {
+		return fmt.Errorf("invalid body %q, 1 to 128 bytes required", opts.Body)
+	}
+
+	if !outTradeNoPattern.MatchString(opts.OutTradeNo) {
+		return fmt.Errorf("invalid out_trade_no %q, 1 to 32 letters, digits or _-|* required", opts.OutTradeNo)
+	}
+
+	if opts.TotalFee <= 0 {
+		return fmt.Errorf("invalid total_fee %d", opts.TotalFee)
+	}
+
+	if net.ParseIP(opts.SpbillCreateIP) == nil {
+		return fmt.Errorf("invalid spbill_create_ip %q", opts.SpbillCreateIP)
+	}
+
+	lengths := []struct {
+		name  string
+		value string
+		max   int
+	}{
+		{"device_info", opts.DeviceInfo, 32},
+		{"attach", opts.Attach, 127},
+		{"fee_type", opts.FeeType, 16},
+		{"goods_tag", opts.GoodsTag, 32},
+		{"notify_url", opts.NotifyURL, 256},
+		{"product_id", opts.ProductID, 32},
+		{"openid", opts.OpenID, 128},
+		{"sub_openid", opts.SubOpenID, 128},
+	}
+	for _, l := range lengths {
+		if len(l.value) > l.max {
+			return fmt.Errorf("invalid %s %q, at most %d bytes", l.name, l.value, l.max)
+		}
+	}
+
+	if opts.LimitPay != "" && opts.LimitPay != NoCredit {
+		return fmt.Errorf("invalid limit_pay %q", opts.LimitPay)
+	}
+
+	if err := validateSignType(opts.SignType); err != nil {
+		return err
+	}
+
+	if !opts.TimeStart.IsZero() && !opts.TimeExpire.IsZero() && opts.TimeExpire.Sub(opts.TimeStart) < time.Minute {
+		return errors.New("time_expire must be at least 1 minute after time_start")
+	}
+
+	return nil
+}
+
+// UnifiedOrderWithOptions creates new order from Weixin with opts.
+func (c *Client) UnifiedOrderWithOptions(opts UnifiedOrderOptions) (*UnifiedOrderRsp, error) {
+	req, err := c.newUnifiedOrderReq(opts)
 	if err != nil {
 		return nil, err
 	}
 
-	reqMap["sign"] = signature(reqMap, c.config.AppKey)
-	xmlStr := toXMLStr(reqMap)
-
 	uri := req.URI()
 	if c.config.SandBox {
 		uri = req.SandBoxURI()
 	}
 
-	data, err := c.doHTTPRequest(uri, xmlStr)
-	if err != nil {
+	rsp := &UnifiedOrderRsp{}
+	if err = c.doRequest(uri, req, rsp); err != nil {
 		return nil, err
 	}
 
-	rsp := &UnifiedOrderRsp{}
-	if err = xml.NewDecoder(bytes.NewReader(data)).Decode(rsp); err != nil {
-		return nil, err
+	return rsp, nil
+}
+
+// newUnifiedOrderReq validates opts with defaults of Config and converts it
+// into the request of /pay/unifiedorder.
+func (c *Client) newUnifiedOrderReq(opts UnifiedOrderOptions) (unifiedOrderReq, error) {
+	if opts.NotifyURL == "" {
+		opts.NotifyURL = c.config.NotifyURL
 	}
 
-	if rsp.ReturnCode != Success {
-		return nil, fmt.Errorf("return code %s, return msg %s", rsp.ReturnCode, rsp.ReturnMsg)
+	if opts.TradeType == "" {
+		opts.TradeType = c.config.TradeType
 	}
 
-	if rsp.ResultCode != Success {
-		return nil, fmt.Errorf("err code %s, err code desc %s", rsp.ErrCode, rsp.ErrCodeDesc)
+	if opts.SignType == "" {
+		opts.SignType = c.config.SignType
 	}
 
-	rspMap, err := toMap(rsp)
-	if err != nil {
-		return nil, err
+	if err := opts.validate(); err != nil {
+		return unifiedOrderReq{}, err
 	}
-	rspSign := signature(rspMap, c.config.AppKey)
-	if rspSign != rspMap["sign"] {
-		return nil, fmt.Errorf("signature failed, expected %s, got %s", rspSign, rspMap["sign"])
+
+	switch opts.TradeType {
+	case JSAPI:
+		if opts.OpenID == "" && opts.SubOpenID == "" {
+			return unifiedOrderReq{}, errors.New("openid or sub_openid required for JSAPI")
+		}
+	case Native:
+		if opts.ProductID == "" {
+			return unifiedOrderReq{}, errors.New("product_id required for NATIVE")
+		}
+	case MWeb:
+		if opts.SceneInfo == nil {
+			return unifiedOrderReq{}, errors.New("scene_info required for MWEB")
+		}
 	}
 
-	return rsp, nil
+	req := unifiedOrderReq{
+		AppID:          c.config.AppID,
+		MchID:          c.config.MchID,
+		SubAppID:       c.config.SubAppID,
+		SubMchID:       c.config.SubMchID,
+		DeviceInfo:     opts.DeviceInfo,
+		NonceStr:       generateNonceStr(),
+		SignType:       opts.SignType,
+		Body:           opts.Body,
+		Attach:         opts.Attach,
+		OutTradeNo:     opts.OutTradeNo,
+		FeeType:        opts.FeeType,
+		TotalFee:       fmt.Sprintf("%d", opts.TotalFee),
+		SpbillCreateIP: opts.SpbillCreateIP,
+		GoodsTag:       opts.GoodsTag,
+		NotifyURL:      opts.NotifyURL,
+		TradeType:      opts.TradeType,
+		ProductID:      opts.ProductID,
+		LimitPay:       opts.LimitPay,
+		OpenID:         opts.OpenID,
+		SubOpenID:      opts.SubOpenID,
+	}
+
+	if opts.Detail != nil {
+		data, err := json.Marshal(opts.Detail)
+		if err != nil {
+			return unifiedOrderReq{}, err
+		}
+		if len(data) > 6000 {
+			return unifiedOrderReq{}, fmt.Errorf("invalid detail, at most 6000 bytes, got %d", len(data))
+		}
+		req.Detail = string(data)
+	}
+
+	if opts.SceneInfo != nil {
+		data, err := json.Marshal(opts.SceneInfo)
+		if err != nil {
+			return unifiedOrderReq{}, err
+		}
+		if len(data) > 256 {
+			return unifiedOrderReq{}, fmt.Errorf("invalid scene_info, at most 256 bytes, got %d", len(data))
+		}
+		req.SceneInfo = string(data)
+	}
+
+	if !opts.TimeStart.IsZero() {
+		req.TimeStart = opts.TimeStart.Format("20060102150405")
+	}
+
+	if !opts.TimeExpire.IsZero() {
+		req.TimeExpire = opts.TimeExpire.Format("20060102150405")
+	}
+
+	if opts.Receipt {
+		req.Receipt = "Y"
+	}
+
+	if opts.ProfitSharing || c.config.ProfitSharing {
+		req.ProfitSharing = "Y"
+	}
+
+	return req, nil
 }
 
-// ToPayment returns Payment from prePayID.
+// ToPayment returns Payment from prePayID. In sandbox mode it is signed with
+// the sandbox sign key cached by UnifiedOrder, Sign is left empty if the key
+// could not be fetched, use NewPayment to get the error.
 func (c *Client) ToPayment(prePayID string) Payment {
+	payment, err := c.NewPayment(prePayID)
+	if err != nil {
+		payment = c.newPayment(prePayID, "")
+		payment.Sign = ""
+	}
+	return payment
+}
+
+// NewPayment returns Payment from prePayID as ToPayment does, or the error of
+// fetching the sandbox sign key in sandbox mode.
+func (c *Client) NewPayment(prePayID string) (Payment, error) {
+	key, err := c.signKey()
+	if err != nil {
+		return Payment{}, err
+	}
+	return c.newPayment(prePayID, key), nil
+}
+
+// newPayment returns Payment from prePayID signed with key.
+func (c *Client) newPayment(prePayID, key string) Payment {
 	nonceStr := generateNonceStr()
 	timestampStr := generateTimestampStr()
+
+	// the App and merchant paying are those of the sub-merchant
+	appID, partnerID := c.config.AppID, c.config.MchID
+	if c.config.SubAppID != "" {
+		appID = c.config.SubAppID
+	}
+	if c.config.SubMchID != "" {
+		partnerID = c.config.SubMchID
+	}
+
 	params := map[string]string{
-		"appid":     c.config.AppID,
-		"partnerid": c.config.MchID,
+		"appid":     appID,
+		"partnerid": partnerID,
 		"prepayid":  prePayID,
 		"noncestr":  nonceStr,
 		"timestamp": timestampStr,
@@ -160,208 +476,342 @@ func (c *Client) ToPayment(prePayID string) Payment {
 	}
 
 	return Payment{
-		AppID:     c.config.AppID,
-		PartnerID: c.config.MchID,
+		AppID:     appID,
+		PartnerID: partnerID,
 		PrepayID:  prePayID,
 		NonceStr:  nonceStr,
 		Timestamp: timestampStr,
 		Package:   "Sign=WXPay",
-		Sign:      signature(params, c.config.AppKey),
+		Sign:      signature(params, key, c.config.SignType),
 	}
 }
 
 // QueryOrder queries order info from Weixin.
 func (c *Client) QueryOrder(transID string, tradeNo string) (*QueryOrderRsp, error) {
-	req := queryOrderReq{
-		AppID:         c.config.AppID,
-		MchID:         c.config.MchID,
+	return c.QueryOrderWithOptions(QueryOrderOptions{
 		TransactionID: transID,
 		OutTradeNo:    tradeNo,
-		NonceStr:      generateNonceStr(),
+	})
+}
+
+// QueryOrderOptions contains all parameters of /pay/orderquery.
+type QueryOrderOptions struct {
+	TransactionID string // 微信订单号，与商户订单号二选一
+	OutTradeNo    string // 商户订单号，与微信订单号二选一
+	SignType      string // 签名类型，默认为Config.SignType
+}
+
+// QueryOrderWithOptions queries order info from Weixin with opts.
+func (c *Client) QueryOrderWithOptions(opts QueryOrderOptions) (*QueryOrderRsp, error) {
+	if opts.SignType == "" {
+		opts.SignType = c.config.SignType
 	}
 
-	reqMap, err := toMap(req)
-	if err != nil {
+	if err := validateSignType(opts.SignType); err != nil {
 		return nil, err
 	}
 
-	reqMap["sign"] = signature(reqMap, c.config.AppKey)
-	xmlStr := toXMLStr(reqMap)
+	req := queryOrderReq{
+		AppID:         c.config.AppID,
+		MchID:         c.config.MchID,
+		SubAppID:      c.config.SubAppID,
+		SubMchID:      c.config.SubMchID,
+		TransactionID: opts.TransactionID,
+		OutTradeNo:    opts.OutTradeNo,
+		NonceStr:      generateNonceStr(),
+		SignType:      opts.SignType,
+	}
 
 	uri := req.URI()
 	if c.config.SandBox {
 		uri = req.SandBoxURI()
 	}
 
-	data, err := c.doHTTPRequest(uri, xmlStr)
-	if err != nil {
+	rsp := &QueryOrderRsp{}
+	if err := c.doRequest(uri, req, rsp); err != nil {
 		return nil, err
 	}
 
-	rsp := &QueryOrderRsp{}
-	if err = xml.NewDecoder(bytes.NewReader(data)).Decode(rsp); err != nil {
-		return nil, err
+	return rsp, nil
+}
+
+// RefundOrder refunds refundFee of an order paid with totalFee.
+func (c *Client) RefundOrder(transID, tradeNo, refundNo string, totalFee, refundFee int) (*RefundOrderRsp, error) {
+	return c.RefundOrderWithOptions(RefundOrderOptions{
+		TransactionID: transID,
+		OutTradeNo:    tradeNo,
+		OutRefundNo:   refundNo,
+		TotalFee:      totalFee,
+		RefundFee:     refundFee,
+	})
+}
+
+// RefundOrderOptions contains all parameters of /secapi/pay/refund.
+type RefundOrderOptions struct {
+	TransactionID string // 微信订单号，与商户订单号二选一
+	OutTradeNo    string // 商户订单号，与微信订单号二选一
+	OutRefundNo   string // 商户退款单号
+	TotalFee      int    // 订单金额
+	RefundFee     int    // 退款金额
+	RefundFeeType string // 退款货币种类，可选
+	RefundDesc    string // 退款原因，可选
+	RefundAccount string // 退款资金来源，可选
+	NotifyURL     string // 退款结果通知url，可选
+	SignType      string // 签名类型，默认为Config.SignType
+}
+
+// RefundOrderWithOptions refunds an order with opts.
+func (c *Client) RefundOrderWithOptions(opts RefundOrderOptions) (*RefundOrderRsp, error) {
+	if opts.SignType == "" {
+		opts.SignType = c.config.SignType
 	}
 
-	if rsp.ReturnCode != Success {
-		return nil, fmt.Errorf("return code %s, return msg %s", rsp.ReturnCode, rsp.ReturnMsg)
+	if err := validateSignType(opts.SignType); err != nil {
+		return nil, err
 	}
 
-	if rsp.ResultCode != Success {
-		return nil, fmt.Errorf("err code %s, err code desc %s", rsp.ErrCode, rsp.ErrCodeDesc)
+	req := refundOrderReq{
+		AppID:         c.config.AppID,
+		MchID:         c.config.MchID,
+		SubAppID:      c.config.SubAppID,
+		SubMchID:      c.config.SubMchID,
+		NonceStr:      generateNonceStr(),
+		SignType:      opts.SignType,
+		TransactionID: opts.TransactionID,
+		OutTradeNo:    opts.OutTradeNo,
+		OutRefundNo:   opts.OutRefundNo,
+		TotalFee:      fmt.Sprintf("%d", opts.TotalFee),
+		RefundFee:     fmt.Sprintf("%d", opts.RefundFee),
+		RefundFeeType: opts.RefundFeeType,
+		RefundDesc:    opts.RefundDesc,
+		RefundAccount: opts.RefundAccount,
+		NotifyURL:     opts.NotifyURL,
 	}
 
-	rspMap, err := toMap(rsp)
-	if err != nil {
-		return nil, err
+	uri := req.URI()
+	if c.config.SandBox {
+		uri = req.SandBoxURI()
 	}
 
-	rspSign := signature(rspMap, c.config.AppKey)
-	if rspSign != rspMap["sign"] {
-		return nil, fmt.Errorf("signature failed, expected %s, got %s", rspSign, rspMap["sign"])
+	rsp := &RefundOrderRsp{}
+	if err := c.doRequest(uri, req, rsp); err != nil {
+		return nil, err
 	}
 
 	return rsp, nil
 }
 
-func (c *Client) RefundOrder(transID, tradeNo, refundNo string, totalFee, refundFee int) (*RefundOrderRsp, error) {
-	req := refundOrderReq{
-		AppID:         c.config.AppID,
-		MchID:         c.config.MchID,
-		NonceStr:      generateNonceStr(),
+// QueryRefund queries refund info from Weixin.
+func (c *Client) QueryRefund(transID, tradeNo, refundNo, refundID string) (*QueryRefundRsp, error) {
+	return c.QueryRefundWithOffset(transID, tradeNo, refundNo, refundID, 0)
+}
+
+// QueryRefundWithOffset queries refund info from Weixin starting at offset.
+// An order with more than 10 refunds must be queried page by page, using
+// transID or tradeNo along with offset.
+func (c *Client) QueryRefundWithOffset(transID, tradeNo, refundNo, refundID string, offset int) (*QueryRefundRsp, error) {
+	return c.QueryRefundWithOptions(QueryRefundOptions{
 		TransactionID: transID,
 		OutTradeNo:    tradeNo,
 		OutRefundNo:   refundNo,
-		TotalFee:      fmt.Sprintf("%d", totalFee),
-		RefundFee:     fmt.Sprintf("%d", refundFee),
+		RefundID:      refundID,
+		Offset:        offset,
+	})
+}
+
+// QueryRefundOptions contains all parameters of /pay/refundquery.
+type QueryRefundOptions struct {
+	TransactionID string // 微信订单号，四选一
+	OutTradeNo    string // 商户订单号，四选一
+	OutRefundNo   string // 商户退款单号，四选一
+	RefundID      string // 微信退款单号，四选一
+	Offset        int    // 偏移量，可选
+	SignType      string // 签名类型，默认为Config.SignType
+}
+
+// QueryRefundWithOptions queries refund info from Weixin with opts.
+func (c *Client) QueryRefundWithOptions(opts QueryRefundOptions) (*QueryRefundRsp, error) {
+	if opts.SignType == "" {
+		opts.SignType = c.config.SignType
 	}
 
-	reqMap, err := toMap(req)
-	if err != nil {
+	if err := validateSignType(opts.SignType); err != nil {
 		return nil, err
 	}
 
-	reqMap["sign"] = signature(reqMap, c.config.AppKey)
-	xmlStr := toXMLStr(reqMap)
+	req := queryRefundReq{
+		AppID:         c.config.AppID,
+		MchID:         c.config.MchID,
+		SubAppID:      c.config.SubAppID,
+		SubMchID:      c.config.SubMchID,
+		NonceStr:      generateNonceStr(),
+		SignType:      opts.SignType,
+		TransactionID: opts.TransactionID,
+		OutTradeNo:    opts.OutTradeNo,
+		OutRefundNo:   opts.OutRefundNo,
+		RefundID:      opts.RefundID,
+	}
+
+	if opts.Offset > 0 {
+		req.Offset = fmt.Sprintf("%d", opts.Offset)
+	}
 
 	uri := req.URI()
 	if c.config.SandBox {
 		uri = req.SandBoxURI()
 	}
 
-	data, err := c.doHTTPRequest(uri, xmlStr)
-	if err != nil {
+	rsp := &QueryRefundRsp{}
+	if err := c.doRequest(uri, req, rsp); err != nil {
 		return nil, err
 	}
 
-	rsp := &RefundOrderRsp{}
-	if err = xml.NewDecoder(bytes.NewReader(data)).Decode(rsp); err != nil {
-		return nil, err
-	}
+	return rsp, nil
+}
 
-	if rsp.ReturnCode != Success {
-		return nil, fmt.Errorf("return code %s, return msg %s", rsp.ReturnCode, rsp.ReturnMsg)
+// DownloadFundFlow downloads the fund flow bill of accountType on billDate,
+// which is formatted as 20060102. It requires client certificates, and is
+// always signed with HMAC-SHA256, the only sign type accepted by the API.
+func (c *Client) DownloadFundFlow(billDate, accountType string) (*FundFlowBill, error) {
+	switch accountType {
+	case AccountBasic, AccountOperation, AccountFees:
+	default:
+		return nil, fmt.Errorf("invalid account_type %q, %s, %s or %s required",
+			accountType, AccountBasic, AccountOperation, AccountFees)
 	}
 
-	if rsp.ResultCode != Success {
-		return nil, fmt.Errorf("err code %s, err code desc %s", rsp.ErrCode, rsp.ErrCodeDesc)
+	req := downloadFundFlowReq{
+		AppID:       c.config.AppID,
+		MchID:       c.config.MchID,
+		SubAppID:    c.config.SubAppID,
+		SubMchID:    c.config.SubMchID,
+		NonceStr:    generateNonceStr(),
+		SignType:    HMACSHA256,
+		BillDate:    billDate,
+		AccountType: accountType,
 	}
 
-	rspMap, err := toMap(rsp)
+	xmlStr, _, err := c.signXML(req)
 	if err != nil {
 		return nil, err
 	}
 
-	rspSign := signature(rspMap, c.config.AppKey)
-	if rspSign != rspMap["sign"] {
-		return nil, fmt.Errorf("signature failed, expected %s, got %s", rspSign, rspMap["sign"])
+	uri := req.URI()
+	if c.config.SandBox {
+		uri = req.SandBoxURI()
 	}
 
-	return rsp, nil
-}
+	data, err := c.doHTTPRequest(uri, xmlStr)
+	if err != nil {
+		return nil, err
+	}
 
-func (c *Client) QueryRefund(transID, tradeNo, refundNo, refundID string) (*QueryRefundRsp, error) {
-	req := queryRefundReq{
-		AppID:         c.config.AppID,
-		MchID:         c.config.MchID,
-		NonceStr:      generateNonceStr(),
-		TransactionID: transID,
-		OutTradeNo:    tradeNo,
-		OutRefundNo:   refundNo,
-		RefundID:      refundID,
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("<xml>")) {
+		rsp := &downloadBillErrRsp{}
+		if err = xml.NewDecoder(bytes.NewReader(data)).Decode(rsp); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("return code %s, return msg %s, err code %s", rsp.ReturnCode, rsp.ReturnMsg, rsp.ErrCode)
 	}
 
-	reqMap, err := toMap(req)
+	return parseFundFlowBill(data)
+}
+
+// AsyncNotify retrieves the asynchronous notification from Weixin.
+func (c *Client) AsyncNotify(req *http.Request) (*AsyncNotifyResult, error) {
+	result, err := c.verifyAsyncNotify(req)
 	if err != nil {
 		return nil, err
 	}
 
-	reqMap["sign"] = signature(reqMap, c.config.AppKey)
-	xmlStr := toXMLStr(reqMap)
-
-	uri := req.URI()
-	if c.config.SandBox {
-		uri = req.SandBoxURI()
+	if result.ResultCode != Success {
+		return nil, fmt.Errorf("err code %s, err code desc %s", result.ErrCode, result.ErrCodeDesc)
 	}
 
-	data, err := c.doHTTPRequest(uri, xmlStr)
+	return result, nil
+}
+
+// verifyAsyncNotify retrieves the asynchronous notification from Weixin and
+// verifies its signature, whatever its result_code.
+func (c *Client) verifyAsyncNotify(req *http.Request) (*AsyncNotifyResult, error) {
+	defer req.Body.Close()
+	data, err := ioutil.ReadAll(req.Body)
 	if err != nil {
 		return nil, err
 	}
 
-	rsp := &QueryRefundRsp{}
-	if err = xml.NewDecoder(bytes.NewReader(data)).Decode(rsp); err != nil {
+	result := &AsyncNotifyResult{}
+	if err = xml.NewDecoder(bytes.NewReader(data)).Decode(result); err != nil {
 		return nil, err
 	}
 
-	if rsp.ReturnCode != Success {
-		return nil, fmt.Errorf("return code %s, return msg %s", rsp.ReturnCode, rsp.ReturnMsg)
+	if result.ReturnCode != Success {
+		return nil, fmt.Errorf("return code %s, return msg %s", result.ReturnCode, result.ReturnMsg)
 	}
 
-	if rsp.ResultCode != Success {
-		return nil, fmt.Errorf("err code %s, err code desc %s", rsp.ErrCode, rsp.ErrCodeDesc)
+	// verify over every field received rather than those known to result
+	rspMap, err := xmlToMap(data)
+	if err != nil {
+		return nil, err
 	}
+	result.decodeIndexed(rspMap)
 
-	rspMap, err := toMap(rsp)
+	key, err := c.signKey()
 	if err != nil {
 		return nil, err
 	}
 
-	rspSign := signature(rspMap, c.config.AppKey)
+	// the notification is signed as the order was placed
+	signType := rspMap["sign_type"]
+	if signType == "" {
+		signType = c.config.SignType
+	}
+
+	rspSign := signature(rspMap, key, signType)
 	if rspSign != rspMap["sign"] {
-		return nil, fmt.Errorf("signature failed, expected %s, got %s", rspSign, rspMap["sign"])
+		return nil, fmt.Errorf("signature failed, expected %s, got %s, result %#v, rspMap %v",
+			rspSign, rspMap["sign"], result, rspMap)
 	}
 
-	return rsp, nil
+	return result, nil
 }
 
-// AsyncNotify retrieves the asynchronous notification from Weixin.
-func (c *Client) AsyncNotify(req *http.Request) (*AsyncNotifyResult, error) {
+// RefundNotify retrieves the asynchronous refund notification from Weixin and
+// decrypts its req_info.
+func (c *Client) RefundNotify(req *http.Request) (*RefundNotifyResult, error) {
 	defer req.Body.Close()
-	result := &AsyncNotifyResult{}
-	if err := xml.NewDecoder(req.Body).Decode(result); err != nil {
+	notify := &refundNotify{}
+	if err := xml.NewDecoder(req.Body).Decode(notify); err != nil {
 		return nil, err
 	}
 
-	if result.ReturnCode != Success {
-		return nil, fmt.Errorf("return code %s, return msg %s", result.ReturnCode, result.ReturnMsg)
+	if notify.ReturnCode != Success {
+		return nil, fmt.Errorf("return code %s, return msg %s", notify.ReturnCode, notify.ReturnMsg)
 	}
 
-	rspMap, err := toMap(result)
+	key, err := c.signKey()
 	if err != nil {
 		return nil, err
 	}
 
-	rspSign := signature(rspMap, c.config.AppKey)
-	if rspSign != rspMap["sign"] {
-		return nil, fmt.Errorf("signature failed, expected %s, got %s, result %#v, rspMap %v",
-			rspSign, rspMap["sign"], result, rspMap)
+	data, err := decryptReqInfo(notify.ReqInfo, key)
+	if err != nil {
+		return nil, err
 	}
 
-	if result.ResultCode != Success {
-		return nil, fmt.Errorf("err code %s, err code desc %s", result.ErrCode, result.ErrCodeDesc)
+	result := &RefundNotifyResult{}
+	if err = xml.NewDecoder(bytes.NewReader(data)).Decode(result); err != nil {
+		return nil, err
 	}
 
+	result.ReturnCode = notify.ReturnCode
+	result.ReturnMsg = notify.ReturnMsg
+	result.AppID = notify.AppID
+	result.MchID = notify.MchID
+	result.SubAppID = notify.SubAppID
+	result.SubMchID = notify.SubMchID
+	result.NonceStr = notify.NonceStr
+
 	return result, nil
 }
 
@@ -374,7 +824,8 @@ func (c *Client) AnswerAsyncNotify(returnCode, returnMsg string) string {
 	return toXMLStr(retMap)
 }
 
-// GetSandBoxSignKey gets sandox sign key from Weixin.
+// GetSandBoxSignKey gets sandbox sign key from Weixin. It is called on demand
+// in sandbox mode, the key is cached and used instead of AppKey.
 func (c *Client) GetSandBoxSignKey() (*GetSandBoxSignKeyRsp, error) {
 	req := getSandBoxSignKeyReq{
 		MchID:    c.config.MchID,
@@ -386,7 +837,7 @@ func (c *Client) GetSandBoxSignKey() (*GetSandBoxSignKeyRsp, error) {
 		return nil, err
 	}
 
-	reqMap["sign"] = signature(reqMap, c.config.AppKey)
+	reqMap["sign"] = signature(reqMap, c.config.AppKey, MD5)
 	xmlStr := toXMLStr(reqMap)
 
 	data, err := c.doHTTPRequest(req.SandBoxURI(), xmlStr)
@@ -406,32 +857,221 @@ func (c *Client) GetSandBoxSignKey() (*GetSandBoxSignKeyRsp, error) {
 	return rsp, nil
 }
 
+// signKey returns the key to sign requests and verify responses with, which
+// is the sandbox sign key fetched from Weixin once in sandbox mode.
+func (c *Client) signKey() (string, error) {
+	if !c.config.SandBox {
+		return c.config.AppKey, nil
+	}
+
+	c.cache.sandBoxMu.Lock()
+	defer c.cache.sandBoxMu.Unlock()
+
+	if c.cache.sandBoxKey == "" {
+		rsp, err := c.GetSandBoxSignKey()
+		if err != nil {
+			return "", err
+		}
+		if rsp.SandBoxSignKey == "" {
+			return "", errors.New("empty sandbox sign key")
+		}
+		c.cache.sandBoxKey = rsp.SandBoxSignKey
+	}
+	return c.cache.sandBoxKey, nil
+}
+
 // AsyncNotifyResult is the result return from Weixin.
 type AsyncNotifyResult struct {
-	ReturnCode    string `xml:"return_code"`    // 返回状态码
-	ReturnMsg     string `xml:"return_msg"`     // 返回信息
-	AppID         string `xml:"appid"`          // 应用ID
-	MchID         string `xml:"mch_id"`         // 商户号
-	DeviceInfo    string `xml:"device_info"`    // 设备号
-	NonceStr      string `xml:"nonce_str"`      // 随机字符串
-	Sign          string `xml:"sign"`           // 签名
-	ResultCode    string `xml:"result_code"`    // 业务结果
-	ErrCode       string `xml:"err_code"`       // 错误代码
-	ErrCodeDesc   string `xml:"err_code_des"`   // 错误代码描述
-	OpenID        string `xml:"openid"`         // 用户标识
-	IsSubscribe   string `xml:"is_subscribe"`   // 是否关注公众账号
-	TradeType     string `xml:"trade_type"`     // 交易类型
-	BankType      string `xml:"bank_type"`      // 付款银行
-	TotalFee      string `xml:"total_fee"`      // 总金额
-	FeeType       string `xml:"fee_type"`       // 货币种类
-	CashFee       string `xml:"cash_fee"`       // 现金支付金额
-	CashFeeType   string `xml:"cash_fee_type"`  // 现金支付货币类型
-	CouponFee     string `xml:"coupon_fee"`     // 代金券或立减优惠金额
-	CouponCount   string `xml:"coupon_count"`   // 代金券或立减优惠使用数量
-	TransactionID string `xml:"transaction_id"` // 微信支付订单号
-	OutTradeNo    string `xml:"out_trade_no"`   // 商户订单号
-	Attach        string `xml:"attach"`         // 商家数据包
-	TimeEnd       string `xml:"time_end"`       // 支付完成时间
+	ReturnCode         string   `xml:"return_code"`          // 返回状态码
+	ReturnMsg          string   `xml:"return_msg"`           // 返回信息
+	AppID              string   `xml:"appid"`                // 应用ID
+	MchID              string   `xml:"mch_id"`               // 商户号
+	SubAppID           string   `xml:"sub_appid"`            // 子商户公众账号ID
+	SubMchID           string   `xml:"sub_mch_id"`           // 子商户号
+	DeviceInfo         string   `xml:"device_info"`          // 设备号
+	NonceStr           string   `xml:"nonce_str"`            // 随机字符串
+	Sign               string   `xml:"sign"`                 // 签名
+	ResultCode         string   `xml:"result_code"`          // 业务结果
+	ErrCode            string   `xml:"err_code"`             // 错误代码
+	ErrCodeDesc        string   `xml:"err_code_des"`         // 错误代码描述
+	OpenID             string   `xml:"openid"`               // 用户标识
+	IsSubscribe        string   `xml:"is_subscribe"`         // 是否关注公众账号
+	SubOpenID          string   `xml:"sub_openid"`           // 用户子标识
+	SubIsSubscribe     string   `xml:"sub_is_subscribe"`     // 是否关注子公众账号
+	TradeType          string   `xml:"trade_type"`           // 交易类型
+	BankType           string   `xml:"bank_type"`            // 付款银行
+	TotalFee           string   `xml:"total_fee"`            // 总金额
+	FeeType            string   `xml:"fee_type"`             // 货币种类
+	CashFee            string   `xml:"cash_fee"`             // 现金支付金额
+	CashFeeType        string   `xml:"cash_fee_type"`        // 现金支付货币类型
+	CouponFee          string   `xml:"coupon_fee"`           // 代金券或立减优惠金额
+	CouponCount        string   `xml:"coupon_count"`         // 代金券或立减优惠使用数量
+	TransactionID      string   `xml:"transaction_id"`       // 微信支付订单号
+	OutTradeNo         string   `xml:"out_trade_no"`         // 商户订单号
+	Attach             string   `xml:"attach"`               // 商家数据包
+	TimeEnd            string   `xml:"time_end"`             // 支付完成时间
+	SettlementTotalFee string   `xml:"settlement_total_fee"` // 应结订单金额
+	PromotionDetail    string   `xml:"promotion_detail"`     // 营销详情
+	Coupons            []Coupon `xml:"-"`                    // 代金券
+}
+
+func (result *AsyncNotifyResult) decodeIndexed(values map[string]string) {
+	result.Coupons = decodeCoupons(values)
+}
+
+// Promotions returns the promotions decoded from PromotionDetail.
+func (result *AsyncNotifyResult) Promotions() ([]Promotion, error) {
+	return decodePromotionDetail(result.PromotionDetail)
+}
+
+type refundNotify struct {
+	ReturnCode string `xml:"return_code"` // 返回状态码
+	ReturnMsg  string `xml:"return_msg"`  // 返回信息
+	AppID      string `xml:"appid"`       // 公众账号ID
+	MchID      string `xml:"mch_id"`      // 退款的商户号
+	SubAppID   string `xml:"sub_appid"`   // 子商户公众账号ID
+	SubMchID   string `xml:"sub_mch_id"`  // 子商户号
+	NonceStr   string `xml:"nonce_str"`   // 随机字符串
+	ReqInfo    string `xml:"req_info"`    // 加密信息
+}
+
+// RefundNotifyResult is the refund result return from Weixin.
+type RefundNotifyResult struct {
+	ReturnCode          string `xml:"-"`                     // 返回状态码
+	ReturnMsg           string `xml:"-"`                     // 返回信息
+	AppID               string `xml:"-"`                     // 公众账号ID
+	MchID               string `xml:"-"`                     // 退款的商户号
+	SubAppID            string `xml:"-"`                     // 子商户公众账号ID
+	SubMchID            string `xml:"-"`                     // 子商户号
+	NonceStr            string `xml:"-"`                     // 随机字符串
+	TransactionID       string `xml:"transaction_id"`        // 微信订单号
+	OutTradeNo          string `xml:"out_trade_no"`          // 商户订单号
+	RefundID            string `xml:"refund_id"`             // 微信退款单号
+	OutRefundNo         string `xml:"out_refund_no"`         // 商户退款单号
+	TotalFee            string `xml:"total_fee"`             // 订单金额
+	SettlementTotalFee  string `xml:"settlement_total_fee"`  // 应结订单金额
+	RefundFee           string `xml:"refund_fee"`            // 申请退款金额
+	SettlementRefundFee string `xml:"settlement_refund_fee"` // 退款金额
+	RefundStatus        string `xml:"refund_status"`         // 退款状态
+	SuccessTime         string `xml:"success_time"`          // 退款成功时间
+	RefundRecvAccout    string `xml:"refund_recv_accout"`    // 退款入账账户
+	RefundAccount       string `xml:"refund_account"`        // 退款资金来源
+	RefundRequestSource string `xml:"refund_request_source"` // 退款发起来源
+}
+
+// doRequest signs req with the algorithm named by its sign_type, posts it to
+// uri and decodes the verified response into rsp.
+func (c *Client) doRequest(uri string, req, rsp interface{}) error {
+	rspMap, signType, err := c.post(uri, req, rsp)
+	if err != nil {
+		return err
+	}
+
+	key, err := c.requestKey(req)
+	if err != nil {
+		return err
+	}
+
+	rspSign := signature(rspMap, key, signType)
+	if rspSign != rspMap["sign"] {
+		return fmt.Errorf("signature failed, expected %s, got %s", rspSign, rspMap["sign"])
+	}
+
+	return nil
+}
+
+// post signs req, posts it to uri and decodes the response into rsp without
+// verifying its signature, for APIs whose responses are not signed. It
+// returns the fields of the response and the sign type used.
+func (c *Client) post(uri string, req, rsp interface{}) (map[string]string, string, error) {
+	xmlStr, signType, err := c.signXML(req)
+	if err != nil {
+		return nil, "", err
+	}
+
+	data, err := c.doHTTPRequest(uri, xmlStr)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err = xml.NewDecoder(bytes.NewReader(data)).Decode(rsp); err != nil {
+		return nil, "", err
+	}
+
+	// the signature covers every field received, including those unknown to
+	// rsp, so the fields are taken from the raw XML rather than from rsp
+	rspMap, err := xmlToMap(data)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if d, ok := rsp.(indexedDecoder); ok {
+		d.decodeIndexed(rspMap)
+	}
+
+	if rspMap["return_code"] != Success {
+		return nil, "", fmt.Errorf("return code %s, return msg %s", rspMap["return_code"], rspMap["return_msg"])
+	}
+
+	if rspMap["result_code"] != Success {
+		return nil, "", fmt.Errorf("err code %s, err code desc %s", rspMap["err_code"], rspMap["err_code_des"])
+	}
+
+	return rspMap, signType, nil
+}
+
+// sandBoxRequest is implemented by requests available in sandbox.
+type sandBoxRequest interface {
+	URI() string
+	SandBoxURI() string
+}
+
+// requestURI returns the URI of req, or its sandbox URI in sandbox mode.
+func (c *Client) requestURI(req sandBoxRequest) string {
+	if c.config.SandBox {
+		return req.SandBoxURI()
+	}
+	return req.URI()
+}
+
+// requestKey returns the key to sign req with. Requests unavailable in sandbox
+// are sent to production, so they are always signed with AppKey.
+func (c *Client) requestKey(req interface{}) (string, error) {
+	if _, ok := req.(sandBoxRequest); ok {
+		return c.signKey()
+	}
+	return c.config.AppKey, nil
+}
+
+// signXML signs req with the algorithm named by its sign_type and encodes it
+// in XML, returning the sign type used.
+func (c *Client) signXML(req interface{}) (string, string, error) {
+	reqMap, err := toMap(req)
+	if err != nil {
+		return "", "", err
+	}
+
+	signType := reqMap["sign_type"]
+	if err = validateSignType(signType); err != nil {
+		return "", "", err
+	}
+
+	key, err := c.requestKey(req)
+	if err != nil {
+		return "", "", err
+	}
+
+	reqMap["sign"] = signature(reqMap, key, signType)
+	return toXMLStr(reqMap), signType, nil
+}
+
+// validateSignType checks signType is MD5 or HMAC-SHA256, empty for MD5.
+func validateSignType(signType string) error {
+	switch signType {
+	case "", MD5, HMACSHA256:
+		return nil
+	}
+	return fmt.Errorf("invalid sign_type %q, %s or %s required", signType, MD5, HMACSHA256)
 }
 
 func (c *Client) doHTTPRequest(uri string, xmlStr string) ([]byte, error) {
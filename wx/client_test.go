@@ -0,0 +1,605 @@
+package wx
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// signedXML signs params with key and encodes them in XML as Weixin does.
+func signedXML(params map[string]string, key, signType string) string {
+	params["sign"] = signature(params, key, signType)
+	return toXMLStr(params)
+}
+
+// newTestServer returns a server answering every request with params signed
+// by testAppKey.
+func newTestServer(params map[string]string, signType string) *httptest.Server {
+	body := signedXML(params, testAppKey, signType)
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, body)
+	}))
+}
+
+func TestRefundOrderCoupons(t *testing.T) {
+	srv := newTestServer(map[string]string{
+		"return_code":         Success,
+		"result_code":         Success,
+		"appid":               "wx2421b1c4370ec43b",
+		"mch_id":              "10000100",
+		"nonce_str":           "NfsMFbUFpdbEhPXP",
+		"transaction_id":      "1008450740201411110005820873",
+		"out_trade_no":        "1415757673",
+		"out_refund_no":       "1415701182",
+		"refund_id":           "2008450740201411110000174436",
+		"refund_fee":          "2",
+		"total_fee":           "3",
+		"cash_fee":            "1",
+		"coupon_refund_fee":   "1",
+		"coupon_refund_count": "2",
+		"coupon_type_0":       "CASH",
+		"coupon_refund_id_0":  "10000",
+		"coupon_refund_fee_0": "1",
+		"coupon_type_1":       "NO_CASH",
+		"coupon_refund_id_1":  "10001",
+		"coupon_refund_fee_1": "0",
+	}, MD5)
+	defer srv.Close()
+
+	c := NewClient(Config{AppKey: testAppKey})
+	rsp := &RefundOrderRsp{}
+	if err := c.doRequest(srv.URL, refundOrderReq{OutRefundNo: "1415701182"}, rsp); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rsp.Coupons) != 2 {
+		t.Fatalf("returned: %d coupons, expected: 2", len(rsp.Coupons))
+	}
+	if rsp.Coupons[1].CouponType != "NO_CASH" || rsp.Coupons[1].CouponRefundID != "10001" {
+		t.Errorf("returned: %#v", rsp.Coupons[1])
+	}
+}
+
+func TestRefundOrderWithOptions(t *testing.T) {
+	srv := newTestServer(map[string]string{
+		"return_code":    Success,
+		"result_code":    Success,
+		"appid":          "wx2421b1c4370ec43b",
+		"mch_id":         "10000100",
+		"nonce_str":      "NfsMFbUFpdbEhPXP",
+		"transaction_id": "1008450740201411110005820873",
+		"out_trade_no":   "1415757673",
+		"out_refund_no":  "1415701182",
+		"refund_id":      "2008450740201411110000174436",
+		"refund_fee":     "1",
+		"total_fee":      "1",
+		"cash_fee":       "1",
+	}, MD5)
+	defer srv.Close()
+
+	target, _ := url.Parse(srv.URL)
+	rewriter := &hostRewriter{target: target}
+	c := NewClient(Config{AppID: "wx2421b1c4370ec43b", MchID: "10000100", AppKey: testAppKey})
+	c.tlsClient.Transport = rewriter
+
+	rsp, err := c.RefundOrderWithOptions(RefundOrderOptions{
+		OutTradeNo:    "1415757673",
+		OutRefundNo:   "1415701182",
+		TotalFee:      1,
+		RefundFee:     1,
+		RefundDesc:    "商品已售完",
+		RefundAccount: RefundSourceRechargeFunds,
+		NotifyURL:     "https://weixin.qq.com/refund",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rsp.RefundID != "2008450740201411110000174436" {
+		t.Errorf("returned: %s, expected: %s", rsp.RefundID, "2008450740201411110000174436")
+	}
+
+	if rewriter.paths[0] != "/secapi/pay/refund" {
+		t.Errorf("returned: %s, expected: /secapi/pay/refund", rewriter.paths[0])
+	}
+	sent, err := xmlToMap([]byte(rewriter.bodies[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent["refund_desc"] != "商品已售完" || sent["notify_url"] != "https://weixin.qq.com/refund" ||
+		sent["refund_account"] != RefundSourceRechargeFunds {
+		t.Errorf("returned: %v", sent)
+	}
+	if sent["sign"] != signature(sent, testAppKey, MD5) {
+		t.Errorf("returned: %s, expected: %s", sent["sign"], signature(sent, testAppKey, MD5))
+	}
+}
+
+func TestQueryRefundIndexed(t *testing.T) {
+	srv := newTestServer(map[string]string{
+		"return_code":             Success,
+		"result_code":             Success,
+		"appid":                   "wx2421b1c4370ec43b",
+		"mch_id":                  "10000100",
+		"nonce_str":               "TeqClE3i0mvn3DrK",
+		"total_refund_count":      "2",
+		"transaction_id":          "1008450740201411110005820873",
+		"out_trade_no":            "1415757673",
+		"total_fee":               "100",
+		"cash_fee":                "100",
+		"refund_count":            "2",
+		"out_refund_no_0":         "1415701182",
+		"refund_id_0":             "2008450740201411110000174436",
+		"refund_fee_0":            "30",
+		"refund_status_0":         RefundSuccess,
+		"refund_success_time_0":   "2017-12-15 13:18:16",
+		"coupon_refund_count_0":   "1",
+		"coupon_type_0_0":         "CASH",
+		"coupon_refund_id_0_0":    "10000",
+		"coupon_refund_fee_0_0":   "10",
+		"out_refund_no_1":         "1415701183",
+		"refund_id_1":             "2008450740201411110000174437",
+		"refund_fee_1":            "70",
+		"refund_status_1":         RefundProcessing,
+		"refund_recv_accout_1":    "支付用户的零钱",
+		"settlement_refund_fee_1": "70",
+	}, MD5)
+	defer srv.Close()
+
+	c := NewClient(Config{AppKey: testAppKey})
+	rsp := &QueryRefundRsp{}
+	if err := c.doRequest(srv.URL, queryRefundReq{OutTradeNo: "1415757673"}, rsp); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rsp.Refunds) != 2 {
+		t.Fatalf("returned: %d refunds, expected: 2", len(rsp.Refunds))
+	}
+
+	first := rsp.Refunds[0]
+	if first.RefundStatus != RefundSuccess || first.RefundSuccessTime != "2017-12-15 13:18:16" {
+		t.Errorf("returned: %#v", first)
+	}
+	if len(first.Coupons) != 1 || first.Coupons[0].CouponRefundFee != "10" {
+		t.Errorf("returned: %#v", first.Coupons)
+	}
+
+	second := rsp.Refunds[1]
+	if second.RefundStatus != RefundProcessing || len(second.Coupons) != 0 {
+		t.Errorf("returned: %#v", second)
+	}
+}
+
+func TestAsyncNotifyCoupons(t *testing.T) {
+	params := map[string]string{
+		"return_code":          Success,
+		"result_code":          Success,
+		"appid":                "wx2421b1c4370ec43b",
+		"mch_id":               "10000100",
+		"nonce_str":            "5d2b6c2a8db53831f7eda20af46e531c",
+		"openid":               "oUpF8uMEb4qRXf22hE3X68TekukE",
+		"trade_type":           "JSAPI",
+		"total_fee":            "100",
+		"settlement_total_fee": "90",
+		"cash_fee":             "80",
+		"coupon_fee":           "20",
+		"coupon_count":         "2",
+		"coupon_type_0":        "CASH",
+		"coupon_id_0":          "10000",
+		"coupon_fee_0":         "15",
+		"coupon_type_1":        "NO_CASH",
+		"coupon_id_1":          "10001",
+		"coupon_fee_1":         "5",
+		"transaction_id":       "1004400740201409030005092168",
+		"out_trade_no":         "1409811653",
+		"time_end":             "20140903131540",
+		"promotion_detail":     `{"promotion_detail":[{"promotion_id":"109519","name":"单品惠","scope":"SINGLE","type":"DISCOUNT","amount":5,"activity_id":"931386","wxpay_contribute":0,"merchant_contribute":0,"other_contribute":5,"goods_detail":[{"goods_id":"a_goods1","goods_remark":"商品备注","quantity":7,"price":1,"discount_amount":4}]}]}`,
+	}
+	body := signedXML(params, testAppKey, MD5)
+
+	c := NewClient(Config{AppKey: testAppKey})
+	req := httptest.NewRequest(http.MethodPost, "/notify", strings.NewReader(body))
+	result, err := c.AsyncNotify(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.SettlementTotalFee != "90" {
+		t.Errorf("returned: %s, expected: %s", result.SettlementTotalFee, "90")
+	}
+	if len(result.Coupons) != 2 || result.Coupons[0].CouponFee != "15" || result.Coupons[1].CouponID != "10001" {
+		t.Errorf("returned: %#v", result.Coupons)
+	}
+
+	promotions, err := result.Promotions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(promotions) != 1 || promotions[0].OtherContribute != 5 || promotions[0].GoodsDetail[0].DiscountAmount != 4 {
+		t.Errorf("returned: %#v", promotions)
+	}
+}
+
+func TestAsyncNotifySignType(t *testing.T) {
+	params := map[string]string{
+		"return_code":    Success,
+		"result_code":    Success,
+		"appid":          "wx2421b1c4370ec43b",
+		"mch_id":         "10000100",
+		"nonce_str":      "5d2b6c2a8db53831f7eda20af46e531c",
+		"sign_type":      HMACSHA256,
+		"total_fee":      "100",
+		"transaction_id": "1004400740201409030005092168",
+		"out_trade_no":   "1409811653",
+	}
+
+	c := NewClient(Config{AppKey: testAppKey, SignType: MD5})
+	req := httptest.NewRequest(http.MethodPost, "/notify", strings.NewReader(signedXML(params, testAppKey, HMACSHA256)))
+	if _, err := c.AsyncNotify(req); err != nil {
+		t.Fatal(err)
+	}
+
+	// notifications without sign_type are signed with the sign type of Config
+	delete(params, "sign_type")
+	req = httptest.NewRequest(http.MethodPost, "/notify", strings.NewReader(signedXML(params, testAppKey, MD5)))
+	if _, err := c.AsyncNotify(req); err != nil {
+		t.Fatal(err)
+	}
+	req = httptest.NewRequest(http.MethodPost, "/notify", strings.NewReader(signedXML(params, testAppKey, HMACSHA256)))
+	if _, err := c.AsyncNotify(req); err == nil {
+		t.Error("expected signature failure without sign_type")
+	}
+}
+
+// hostRewriter sends every request to the test server, recording their paths
+// and bodies.
+type hostRewriter struct {
+	target *url.URL
+	paths  []string
+	bodies []string
+}
+
+func (h *hostRewriter) RoundTrip(req *http.Request) (*http.Response, error) {
+	h.paths = append(h.paths, req.URL.Path)
+	if req.Body != nil {
+		data, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		h.bodies = append(h.bodies, string(data))
+		req.Body = ioutil.NopCloser(bytes.NewReader(data))
+	}
+	req.URL.Scheme = h.target.Scheme
+	req.URL.Host = h.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestSandBoxSignKey(t *testing.T) {
+	const sandBoxKey = "013467007045764c22c32f7bbc3bafd1"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/getsignkey") {
+			fmt.Fprintf(w, "<xml><return_code>SUCCESS</return_code><mch_id>10000100</mch_id><sandbox_signkey>%s</sandbox_signkey></xml>", sandBoxKey)
+			return
+		}
+
+		params := map[string]string{
+			"return_code": Success,
+			"result_code": Success,
+			"appid":       "wx2421b1c4370ec43b",
+			"mch_id":      "10000100",
+			"nonce_str":   "IITRi8Iabbblz1Jc",
+			"trade_type":  "APP",
+			"prepay_id":   "wx201411101639507cbf6ffd8b0779950874",
+		}
+		fmt.Fprint(w, signedXML(params, sandBoxKey, MD5))
+	}))
+	defer srv.Close()
+
+	target, _ := url.Parse(srv.URL)
+	rewriter := &hostRewriter{target: target}
+	c := NewClient(Config{AppKey: testAppKey, SandBox: true, TradeType: "APP"})
+	c.tlsClient.Transport = rewriter
+
+	for i := 0; i < 2; i++ {
+		rsp, err := c.UnifiedOrder(SandBoxPaySuccess, "test", fmt.Sprintf("order%d", i), "127.0.0.1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rsp.PrepayID != "wx201411101639507cbf6ffd8b0779950874" {
+			t.Errorf("returned: %s", rsp.PrepayID)
+		}
+	}
+
+	expected := "/sandboxnew/pay/getsignkey,/sandboxnew/pay/unifiedorder,/sandboxnew/pay/unifiedorder"
+	if strings.Join(rewriter.paths, ",") != expected {
+		t.Errorf("returned: %v, expected: %s", rewriter.paths, expected)
+	}
+
+	payment := c.ToPayment("wx201411101639507cbf6ffd8b0779950874")
+	params := map[string]string{
+		"appid":     payment.AppID,
+		"partnerid": payment.PartnerID,
+		"prepayid":  payment.PrepayID,
+		"noncestr":  payment.NonceStr,
+		"timestamp": payment.Timestamp,
+		"package":   payment.Package,
+	}
+	if sign := signature(params, sandBoxKey, MD5); payment.Sign != sign {
+		t.Errorf("returned: %s, expected: %s", payment.Sign, sign)
+	}
+
+	// red packets are unavailable in sandbox, so they are sent to production
+	// signed with AppKey
+	if _, err := c.QueryRedPack("0010010404201411170000046545"); err != nil {
+		t.Fatal(err)
+	}
+	if path := rewriter.paths[len(rewriter.paths)-1]; path != "/mmpaymkttransfers/gethbinfo" {
+		t.Errorf("returned: %s, expected: /mmpaymkttransfers/gethbinfo", path)
+	}
+	sent, err := xmlToMap([]byte(rewriter.bodies[len(rewriter.bodies)-1]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent["sign"] != signature(sent, testAppKey, MD5) {
+		t.Errorf("returned: %v, expected signed with AppKey", sent)
+	}
+}
+
+func TestUnifiedOrderOptionsValidate(t *testing.T) {
+	valid := UnifiedOrderOptions{
+		Body:           "腾讯充值中心-QQ会员充值",
+		OutTradeNo:     "20150806125346",
+		TotalFee:       88,
+		SpbillCreateIP: "123.12.12.123",
+	}
+	if err := valid.validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	invalid := []func(*UnifiedOrderOptions){
+		func(o *UnifiedOrderOptions) { o.Body = "" },
+		func(o *UnifiedOrderOptions) { o.Body = strings.Repeat("x", 129) },
+		func(o *UnifiedOrderOptions) { o.OutTradeNo = "order#1" },
+		func(o *UnifiedOrderOptions) { o.OutTradeNo = strings.Repeat("1", 33) },
+		func(o *UnifiedOrderOptions) { o.TotalFee = 0 },
+		func(o *UnifiedOrderOptions) { o.SpbillCreateIP = "localhost" },
+		func(o *UnifiedOrderOptions) { o.Attach = strings.Repeat("x", 128) },
+		func(o *UnifiedOrderOptions) { o.LimitPay = "credit" },
+		func(o *UnifiedOrderOptions) {
+			o.TimeStart = time.Date(2018, 1, 1, 0, 0, 0, 0, time.Local)
+			o.TimeExpire = o.TimeStart.Add(30 * time.Second)
+		},
+	}
+	for i, modify := range invalid {
+		opts := valid
+		modify(&opts)
+		if err := opts.validate(); err == nil {
+			t.Errorf("case %d: expected validation error", i)
+		}
+	}
+}
+
+func TestWithPEMCertificate(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "10000100"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	c := NewClient(Config{MchID: "10000100"})
+	c.tlsClient.Transport.(*http.Transport).IdleConnTimeout = 42 * time.Second
+	c.tlsClient.Timeout = 5 * time.Second
+
+	if err = c.WithPEMCertificate(certPEM, keyPEM); err != nil {
+		t.Fatal(err)
+	}
+
+	tr := c.tlsClient.Transport.(*http.Transport)
+	if len(tr.TLSClientConfig.Certificates) != 1 {
+		t.Errorf("returned: %d certificates, expected: 1", len(tr.TLSClientConfig.Certificates))
+	}
+	if tr.TLSClientConfig.RootCAs != nil {
+		t.Error("expected system root CAs")
+	}
+	if tr.IdleConnTimeout != 42*time.Second || c.tlsClient.Timeout != 5*time.Second {
+		t.Error("expected transport settings to be preserved")
+	}
+}
+
+func TestWithPKCS12Certificate(t *testing.T) {
+	// apiclient_cert.p12 is issued to 10000100, encrypted with the MchID as
+	// Weixin does
+	data, err := ioutil.ReadFile("testdata/apiclient_cert.p12")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var peers []string
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, cert := range r.TLS.PeerCertificates {
+			peers = append(peers, cert.Subject.CommonName)
+		}
+	}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	srv.StartTLS()
+	defer srv.Close()
+
+	c := NewClient(Config{MchID: "10000100"})
+	original := c.tlsClient.Transport.(*http.Transport)
+	original.TLSClientConfig.RootCAs = x509.NewCertPool()
+	original.TLSClientConfig.RootCAs.AddCert(srv.Certificate())
+
+	if err = c.WithPKCS12Certificate(data, "wrong"); err == nil {
+		t.Error("expected failure with wrong password")
+	}
+	if err = c.WithPKCS12Certificate(data, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	tr := c.tlsClient.Transport.(*http.Transport)
+	if tr == original || len(original.TLSClientConfig.Certificates) != 0 {
+		t.Error("expected the transport to be cloned")
+	}
+
+	rsp, err := c.tlsClient.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsp.Body.Close()
+
+	if len(peers) != 1 || peers[0] != "10000100" {
+		t.Errorf("returned: %v, expected: [10000100]", peers)
+	}
+}
+
+func TestVerifyUnknownFields(t *testing.T) {
+	srv := newTestServer(map[string]string{
+		"return_code":          Success,
+		"result_code":          Success,
+		"appid":                "wx2421b1c4370ec43b",
+		"mch_id":               "10000100",
+		"nonce_str":            "IITRi8Iabbblz1Jc",
+		"trade_state":          "SUCCESS",
+		"out_trade_no":         "1415757673",
+		"total_fee":            "100",
+		"field_added_recently": "value",
+		"coupon_count":         "1",
+		"coupon_id_0":          "10000",
+		"coupon_fee_0":         "10",
+	}, MD5)
+	defer srv.Close()
+
+	c := NewClient(Config{AppKey: testAppKey})
+	rsp := &QueryOrderRsp{}
+	if err := c.doRequest(srv.URL, queryOrderReq{OutTradeNo: "1415757673"}, rsp); err != nil {
+		t.Fatal(err)
+	}
+
+	if rsp.TradeState != "SUCCESS" || len(rsp.Coupons) != 1 {
+		t.Errorf("returned: %#v", rsp)
+	}
+}
+
+func TestQueryOrderSignType(t *testing.T) {
+	srv := newTestServer(map[string]string{
+		"return_code":  Success,
+		"result_code":  Success,
+		"appid":        "wx2421b1c4370ec43b",
+		"mch_id":       "10000100",
+		"nonce_str":    "IITRi8Iabbblz1Jc",
+		"trade_state":  "SUCCESS",
+		"out_trade_no": "1415757673",
+	}, HMACSHA256)
+	defer srv.Close()
+
+	target, _ := url.Parse(srv.URL)
+	rewriter := &hostRewriter{target: target}
+	c := NewClient(Config{AppKey: testAppKey, SignType: MD5})
+	c.tlsClient.Transport = rewriter
+
+	rsp, err := c.QueryOrderWithOptions(QueryOrderOptions{OutTradeNo: "1415757673", SignType: HMACSHA256})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rsp.TradeState != "SUCCESS" {
+		t.Errorf("returned: %s, expected: SUCCESS", rsp.TradeState)
+	}
+
+	params, err := xmlToMap([]byte(rewriter.bodies[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params["sign_type"] != HMACSHA256 || params["sign"] != signature(params, testAppKey, HMACSHA256) {
+		t.Errorf("returned: %v, expected signed with %s", params, HMACSHA256)
+	}
+
+	if _, err = c.QueryOrder("", "1415757673"); err == nil {
+		t.Error("expected signature failure with the MD5 of Config")
+	}
+	if _, err = c.QueryOrderWithOptions(QueryOrderOptions{OutTradeNo: "1415757673", SignType: "SHA1"}); err == nil {
+		t.Error("expected failure with invalid sign type")
+	}
+}
+
+func TestQueryRefundSignType(t *testing.T) {
+	srv := newTestServer(map[string]string{
+		"return_code":     Success,
+		"result_code":     Success,
+		"appid":           "wx2421b1c4370ec43b",
+		"mch_id":          "10000100",
+		"nonce_str":       "TeqClE3i0mvn3DrK",
+		"out_trade_no":    "1415757673",
+		"refund_count":    "1",
+		"out_refund_no_0": "1415701182",
+		"refund_status_0": RefundSuccess,
+	}, HMACSHA256)
+	defer srv.Close()
+
+	target, _ := url.Parse(srv.URL)
+	rewriter := &hostRewriter{target: target}
+	c := NewClient(Config{AppKey: testAppKey, SignType: MD5})
+	c.tlsClient.Transport = rewriter
+
+	rsp, err := c.QueryRefundWithOptions(QueryRefundOptions{OutTradeNo: "1415757673", Offset: 10, SignType: HMACSHA256})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rsp.OutTradeNo != "1415757673" {
+		t.Errorf("returned: %s, expected: 1415757673", rsp.OutTradeNo)
+	}
+
+	params, err := xmlToMap([]byte(rewriter.bodies[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params["offset"] != "10" || params["sign_type"] != HMACSHA256 || params["sign"] != signature(params, testAppKey, HMACSHA256) {
+		t.Errorf("returned: %v, expected signed with %s", params, HMACSHA256)
+	}
+
+	if _, err = c.QueryRefund("", "1415757673", "", ""); err == nil {
+		t.Error("expected signature failure with the MD5 of Config")
+	}
+	if _, err = c.QueryRefundWithOptions(QueryRefundOptions{OutTradeNo: "1415757673", SignType: "SHA1"}); err == nil {
+		t.Error("expected failure with invalid sign type")
+	}
+}
+
+func TestDownloadFundFlowAccountType(t *testing.T) {
+	target, _ := url.Parse("http://127.0.0.1:1")
+	rewriter := &hostRewriter{target: target}
+	c := NewClient(Config{AppKey: testAppKey})
+	c.tlsClient.Transport = rewriter
+
+	if _, err := c.DownloadFundFlow("20171103", "basic"); err == nil {
+		t.Error("expected failure with invalid account type")
+	}
+	if len(rewriter.paths) != 0 {
+		t.Errorf("returned: %v, expected no request sent", rewriter.paths)
+	}
+}
@@ -0,0 +1,68 @@
+package wx
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// NotifyHandler handles a verified payment notification, successful or not as
+// told by its ResultCode, returning an error makes Weixin notify again later.
+type NotifyHandler func(result *AsyncNotifyResult) error
+
+// NotifyMux routes payment notifications to handlers by sub_mch_id in
+// service provider mode.
+type NotifyMux struct {
+	client   *Client
+	mu       sync.RWMutex
+	handlers map[string]NotifyHandler
+	fallback NotifyHandler
+}
+
+// NewNotifyMux returns a *NotifyMux verifying notifications with client.
+func NewNotifyMux(client *Client) *NotifyMux {
+	return &NotifyMux{
+		client:   client,
+		handlers: map[string]NotifyHandler{},
+	}
+}
+
+// Handle registers the handler for notifications of subMchID, an empty
+// subMchID registers the handler for notifications of any other merchant.
+func (m *NotifyMux) Handle(subMchID string, handler NotifyHandler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if subMchID == "" {
+		m.fallback = handler
+		return
+	}
+	m.handlers[subMchID] = handler
+}
+
+func (m *NotifyMux) handler(subMchID string) NotifyHandler {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if h, ok := m.handlers[subMchID]; ok {
+		return h
+	}
+	return m.fallback
+}
+
+// ServeHTTP verifies the notification, dispatches it and answers Weixin. The
+// reason of failures is not answered, as it may reveal the signature expected.
+func (m *NotifyMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+
+	result, err := m.client.verifyAsyncNotify(r)
+	if err != nil {
+		fmt.Fprint(w, m.client.AnswerAsyncNotify("FAIL", "invalid notification"))
+		return
+	}
+
+	h := m.handler(result.SubMchID)
+	if h == nil || h(result) != nil {
+		fmt.Fprint(w, m.client.AnswerAsyncNotify("FAIL", "failed to handle notification"))
+		return
+	}
+	fmt.Fprint(w, m.client.AnswerAsyncNotify(Success, "OK"))
+}
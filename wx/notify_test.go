@@ -0,0 +1,78 @@
+package wx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNotifyMux(t *testing.T) {
+	c := NewClient(Config{AppKey: testAppKey})
+	mux := NewNotifyMux(c)
+
+	var routed []string
+	mux.Handle("1900000109", func(result *AsyncNotifyResult) error {
+		routed = append(routed, "store:"+result.OutTradeNo)
+		return nil
+	})
+	mux.Handle("", func(result *AsyncNotifyResult) error {
+		routed = append(routed, "fallback:"+result.OutTradeNo)
+		return nil
+	})
+
+	for _, subMchID := range []string{"1900000109", "1900000110"} {
+		params := map[string]string{
+			"return_code":    Success,
+			"result_code":    Success,
+			"appid":          "wx2421b1c4370ec43b",
+			"mch_id":         "10000100",
+			"sub_mch_id":     subMchID,
+			"sub_openid":     "oUpF8uMEb4qRXf22hE3X68TekukE",
+			"nonce_str":      "5d2b6c2a8db53831f7eda20af46e531c",
+			"total_fee":      "1",
+			"transaction_id": "1004400740201409030005092168",
+			"out_trade_no":   "order-" + subMchID,
+		}
+		body := signedXML(params, testAppKey, MD5)
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/notify", strings.NewReader(body)))
+		if !strings.Contains(w.Body.String(), Success) {
+			t.Errorf("returned: %s", w.Body.String())
+		}
+	}
+
+	expected := "store:order-1900000109,fallback:order-1900000110"
+	if strings.Join(routed, ",") != expected {
+		t.Errorf("returned: %v, expected: %s", routed, expected)
+	}
+
+	// failed payments are dispatched as well
+	params := map[string]string{
+		"return_code":  Success,
+		"result_code":  "FAIL",
+		"err_code":     "NOTENOUGH",
+		"mch_id":       "10000100",
+		"sub_mch_id":   "1900000109",
+		"nonce_str":    "5d2b6c2a8db53831f7eda20af46e531c",
+		"out_trade_no": "failed-1900000109",
+	}
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/notify", strings.NewReader(signedXML(params, testAppKey, MD5))))
+	if !strings.Contains(w.Body.String(), Success) || routed[len(routed)-1] != "store:failed-1900000109" {
+		t.Errorf("returned: %s, routed: %v", w.Body.String(), routed)
+	}
+
+	// forged notifications are rejected without revealing the signature
+	forged := signedXML(params, "0123456789abcdef0123456789abcdef", MD5)
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/notify", strings.NewReader(forged)))
+	rsp, err := xmlToMap(w.Body.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rsp["return_code"] != "FAIL" || rsp["return_msg"] != "invalid notification" {
+		t.Errorf("returned: %v", rsp)
+	}
+}
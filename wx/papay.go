@@ -0,0 +1,553 @@
+package wx
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// constants for contract.
+const (
+	ContractSigned     = "0"      // 签约中
+	ContractTerminated = "1"      // 解约
+	ContractAdd        = "ADD"    // 签约
+	ContractDelete     = "DELETE" // 解约
+)
+
+const entrustWebURI = "https://api.mch.weixin.qq.com/papay/entrustweb"
+
+// ContractOptions contains parameters to sign a contract.
+type ContractOptions struct {
+	PlanID                 string // 模板id
+	ContractCode           string // 签约协议号
+	RequestSerial          int64  // 请求序列号
+	ContractDisplayAccount string // 用户账户展示名称
+	NotifyURL              string // 签约、解约结果通知地址
+}
+
+func (c *Client) contractParams(opts ContractOptions) map[string]string {
+	return map[string]string{
+		"appid":                    c.config.AppID,
+		"mch_id":                   c.config.MchID,
+		"plan_id":                  opts.PlanID,
+		"contract_code":            opts.ContractCode,
+		"request_serial":           fmt.Sprintf("%d", opts.RequestSerial),
+		"contract_display_account": opts.ContractDisplayAccount,
+		"notify_url":               opts.NotifyURL,
+		"timestamp":                fmt.Sprintf("%d", time.Now().Unix()),
+	}
+}
+
+// EntrustWebURL returns the URL to sign a contract in Weixin official
+// accounts, which should be opened by the user in Weixin.
+func (c *Client) EntrustWebURL(opts ContractOptions) string {
+	params := c.contractParams(opts)
+	params["version"] = "1.0"
+	params["sign"] = signature(params, c.config.AppKey, MD5)
+
+	values := url.Values{}
+	for k, v := range params {
+		values.Set(k, v)
+	}
+	return entrustWebURI + "?" + values.Encode()
+}
+
+// MiniProgramContractParams returns the extraData to sign a contract in mini
+// programs. The notify_url is encoded once after signing as required.
+func (c *Client) MiniProgramContractParams(opts ContractOptions) map[string]string {
+	params := c.contractParams(opts)
+	params["sign"] = signature(params, c.config.AppKey, MD5)
+	params["notify_url"] = url.QueryEscape(opts.NotifyURL)
+	return params
+}
+
+// PreEntrustWeb gets pre_entrustweb_id to sign a contract in Apps.
+func (c *Client) PreEntrustWeb(opts ContractOptions) (*PreEntrustWebRsp, error) {
+	params := c.contractParams(opts)
+	req := preEntrustWebReq{
+		AppID:                  params["appid"],
+		MchID:                  params["mch_id"],
+		PlanID:                 params["plan_id"],
+		ContractCode:           params["contract_code"],
+		RequestSerial:          params["request_serial"],
+		ContractDisplayAccount: params["contract_display_account"],
+		NotifyURL:              params["notify_url"],
+		Version:                "1.0",
+		Timestamp:              params["timestamp"],
+	}
+
+	rsp := &PreEntrustWebRsp{}
+	if err := c.doRequest(req.URI(), req, rsp); err != nil {
+		return nil, err
+	}
+
+	return rsp, nil
+}
+
+// QueryContract queries a contract by contractID, or by planID and
+// contractCode.
+func (c *Client) QueryContract(contractID, planID, contractCode string) (*QueryContractRsp, error) {
+	req := queryContractReq{
+		AppID:        c.config.AppID,
+		MchID:        c.config.MchID,
+		ContractID:   contractID,
+		PlanID:       planID,
+		ContractCode: contractCode,
+		Version:      "1.0",
+	}
+
+	rsp := &QueryContractRsp{}
+	if err := c.doRequest(req.URI(), req, rsp); err != nil {
+		return nil, err
+	}
+
+	return rsp, nil
+}
+
+// DeleteContract terminates a contract by contractID, or by planID and
+// contractCode.
+func (c *Client) DeleteContract(contractID, planID, contractCode, remark string) (*DeleteContractRsp, error) {
+	req := deleteContractReq{
+		AppID:                     c.config.AppID,
+		MchID:                     c.config.MchID,
+		ContractID:                contractID,
+		PlanID:                    planID,
+		ContractCode:              contractCode,
+		ContractTerminationRemark: remark,
+		Version:                   "1.0",
+	}
+
+	rsp := &DeleteContractRsp{}
+	if err := c.doRequest(req.URI(), req, rsp); err != nil {
+		return nil, err
+	}
+
+	return rsp, nil
+}
+
+// PapPayApplyOptions contains all parameters of /pay/pappayapply.
+type PapPayApplyOptions struct {
+	Body           string       // 商品描述
+	Detail         *OrderDetail // 商品详情，可选
+	Attach         string       // 附加数据，可选
+	OutTradeNo     string       // 商户订单号
+	TotalFee       int          // 总金额
+	FeeType        string       // 货币类型，可选
+	SpbillCreateIP string       // 终端IP，可选
+	GoodsTag       string       // 商品标记，可选
+	NotifyURL      string       // 回调通知url，默认为Config.NotifyURL
+	ContractID     string       // 委托代扣协议id
+	SignType       string       // 签名类型，默认为Config.SignType
+}
+
+func (opts PapPayApplyOptions) validate() error {
+	if opts.Body == "" || len(opts.Body) > 128 {
+		return fmt.Errorf("invalid body %q, 1 to 128 bytes required", opts.Body)
+	}
+
+	if !outTradeNoPattern.MatchString(opts.OutTradeNo) {
+		return fmt.Errorf("invalid out_trade_no %q, 1 to 32 letters, digits or _-|* required", opts.OutTradeNo)
+	}
+
+	if opts.TotalFee <= 0 {
+		return fmt.Errorf("invalid total_fee %d", opts.TotalFee)
+	}
+
+	if opts.SpbillCreateIP != "" && net.ParseIP(opts.SpbillCreateIP) == nil {
+		return fmt.Errorf("invalid spbill_create_ip %q", opts.SpbillCreateIP)
+	}
+
+	if opts.ContractID == "" {
+		return errors.New("contract_id required")
+	}
+
+	return validateSignType(opts.SignType)
+}
+
+// PapPayApply deducts from a user with the signed contract in opts. The
+// result is notified to opts.NotifyURL.
+func (c *Client) PapPayApply(opts PapPayApplyOptions) (*PapPayApplyRsp, error) {
+	if opts.NotifyURL == "" {
+		opts.NotifyURL = c.config.NotifyURL
+	}
+
+	if opts.SignType == "" {
+		opts.SignType = c.config.SignType
+	}
+
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	req := papPayApplyReq{
+		AppID:          c.config.AppID,
+		MchID:          c.config.MchID,
+		NonceStr:       generateNonceStr(),
+		SignType:       opts.SignType,
+		Body:           opts.Body,
+		Attach:         opts.Attach,
+		OutTradeNo:     opts.OutTradeNo,
+		TotalFee:       fmt.Sprintf("%d", opts.TotalFee),
+		FeeType:        opts.FeeType,
+		SpbillCreateIP: opts.SpbillCreateIP,
+		GoodsTag:       opts.GoodsTag,
+		NotifyURL:      opts.NotifyURL,
+		TradeType:      "PAP",
+		ContractID:     opts.ContractID,
+	}
+
+	if opts.Detail != nil {
+		data, err := json.Marshal(opts.Detail)
+		if err != nil {
+			return nil, err
+		}
+		req.Detail = string(data)
+	}
+
+	rsp := &PapPayApplyRsp{}
+	if err := c.doRequest(req.URI(), req, rsp); err != nil {
+		return nil, err
+	}
+
+	return rsp, nil
+}
+
+// ContractOrderOptions contains all parameters of /pay/contractorder, an
+// order of /pay/unifiedorder along with the contract to sign on payment.
+type ContractOrderOptions struct {
+	UnifiedOrderOptions
+	ContractMchID string          // 签约商户号，默认为MchID
+	ContractAppID string          // 签约appid，默认为AppID
+	Contract      ContractOptions // 签约信息，NotifyURL为签约信息通知url
+}
+
+// ContractOrder creates an order and signs a contract on payment, the
+// contract is signed with opts.ContractMchID which could differ from MchID.
+func (c *Client) ContractOrder(opts ContractOrderOptions) (*ContractOrderRsp, error) {
+	order, err := c.newUnifiedOrderReq(opts.UnifiedOrderOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	// sub-merchants, receipts and profit sharing are not supported, as
+	// contracts are signed by merchants
+	if order.SubAppID != "" || order.SubMchID != "" || order.SubOpenID != "" {
+		return nil, errors.New("sub_appid, sub_mch_id and sub_openid not supported")
+	}
+	if order.Receipt != "" || order.ProfitSharing != "" {
+		return nil, errors.New("receipt and profit_sharing not supported")
+	}
+
+	if order.TradeType == JSAPI && order.OpenID == "" {
+		return nil, errors.New("openid required for JSAPI")
+	}
+
+	if opts.Contract.PlanID == "" || opts.Contract.ContractCode == "" {
+		return nil, errors.New("plan_id and contract_code required")
+	}
+
+	if opts.ContractMchID == "" {
+		opts.ContractMchID = c.config.MchID
+	}
+
+	if opts.ContractAppID == "" {
+		opts.ContractAppID = c.config.AppID
+	}
+
+	req := contractOrderReq{
+		AppID:                  order.AppID,
+		MchID:                  order.MchID,
+		ContractMchID:          opts.ContractMchID,
+		ContractAppID:          opts.ContractAppID,
+		DeviceInfo:             order.DeviceInfo,
+		NonceStr:               order.NonceStr,
+		SignType:               order.SignType,
+		Body:                   order.Body,
+		Detail:                 order.Detail,
+		Attach:                 order.Attach,
+		OutTradeNo:             order.OutTradeNo,
+		FeeType:                order.FeeType,
+		TotalFee:               order.TotalFee,
+		SpbillCreateIP:         order.SpbillCreateIP,
+		TimeStart:              order.TimeStart,
+		TimeExpire:             order.TimeExpire,
+		GoodsTag:               order.GoodsTag,
+		NotifyURL:              order.NotifyURL,
+		TradeType:              order.TradeType,
+		ProductID:              order.ProductID,
+		LimitPay:               order.LimitPay,
+		OpenID:                 order.OpenID,
+		SceneInfo:              order.SceneInfo,
+		PlanID:                 opts.Contract.PlanID,
+		ContractCode:           opts.Contract.ContractCode,
+		RequestSerial:          fmt.Sprintf("%d", opts.Contract.RequestSerial),
+		ContractDisplayAccount: opts.Contract.ContractDisplayAccount,
+		ContractNotifyURL:      opts.Contract.NotifyURL,
+	}
+
+	rsp := &ContractOrderRsp{}
+	if err := c.doRequest(req.URI(), req, rsp); err != nil {
+		return nil, err
+	}
+
+	return rsp, nil
+}
+
+// ContractNotify retrieves the asynchronous notification of signing or
+// terminating a contract from Weixin.
+func (c *Client) ContractNotify(req *http.Request) (*ContractNotifyResult, error) {
+	defer req.Body.Close()
+	data, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ContractNotifyResult{}
+	if err = xml.NewDecoder(bytes.NewReader(data)).Decode(result); err != nil {
+		return nil, err
+	}
+
+	if result.ReturnCode != Success {
+		return nil, fmt.Errorf("return code %s, return msg %s", result.ReturnCode, result.ReturnMsg)
+	}
+
+	rspMap, err := xmlToMap(data)
+	if err != nil {
+		return nil, err
+	}
+
+	rspSign := signature(rspMap, c.config.AppKey, MD5)
+	if rspSign != rspMap["sign"] {
+		return nil, fmt.Errorf("signature failed, expected %s, got %s", rspSign, rspMap["sign"])
+	}
+
+	if result.ResultCode != Success {
+		return nil, fmt.Errorf("err code %s, err code desc %s", result.ErrCode, result.ErrCodeDesc)
+	}
+
+	return result, nil
+}
+
+type preEntrustWebReq struct {
+	XMLName                xml.Name `xml:"xml"`
+	AppID                  string   `xml:"appid"`                    // 应用ID
+	MchID                  string   `xml:"mch_id"`                   // 商户号
+	PlanID                 string   `xml:"plan_id"`                  // 模板id
+	ContractCode           string   `xml:"contract_code"`            // 签约协议号
+	RequestSerial          string   `xml:"request_serial"`           // 请求序列号
+	ContractDisplayAccount string   `xml:"contract_display_account"` // 用户账户展示名称
+	NotifyURL              string   `xml:"notify_url"`               // 回调通知url
+	Version                string   `xml:"version"`                  // 版本号
+	Timestamp              string   `xml:"timestamp"`                // 时间戳
+}
+
+func (req preEntrustWebReq) URI() string {
+	return "https://api.mch.weixin.qq.com/papay/preentrustweb"
+}
+
+// PreEntrustWebRsp is the response returned by /papay/preentrustweb.
+type PreEntrustWebRsp struct {
+	XMLName         xml.Name `xml:"xml"`
+	ReturnCode      string   `xml:"return_code"`       // 返回状态码
+	ReturnMsg       string   `xml:"return_msg"`        // 返回信息
+	ResultCode      string   `xml:"result_code"`       // 业务结果
+	ErrCode         string   `xml:"err_code"`          // 错误代码
+	ErrCodeDesc     string   `xml:"err_code_des"`      // 错误代码描述
+	Sign            string   `xml:"sign"`              // 签名
+	PreEntrustWebID string   `xml:"pre_entrustweb_id"` // 预签约ID
+}
+
+type queryContractReq struct {
+	XMLName      xml.Name `xml:"xml"`
+	AppID        string   `xml:"appid"`         // 公众账号id
+	MchID        string   `xml:"mch_id"`        // 商户号
+	ContractID   string   `xml:"contract_id"`   // 委托代扣协议id
+	PlanID       string   `xml:"plan_id"`       // 模板id
+	ContractCode string   `xml:"contract_code"` // 签约协议号
+	Version      string   `xml:"version"`       // 版本号
+}
+
+func (req queryContractReq) URI() string {
+	return "https://api.mch.weixin.qq.com/papay/querycontract"
+}
+
+// QueryContractRsp is the response returned by /papay/querycontract.
+type QueryContractRsp struct {
+	XMLName                   xml.Name `xml:"xml"`
+	ReturnCode                string   `xml:"return_code"`                 // 返回状态码
+	ReturnMsg                 string   `xml:"return_msg"`                  // 返回信息
+	ResultCode                string   `xml:"result_code"`                 // 业务结果
+	ErrCode                   string   `xml:"err_code"`                    // 错误代码
+	ErrCodeDesc               string   `xml:"err_code_des"`                // 错误代码描述
+	AppID                     string   `xml:"appid"`                       // 公众账号id
+	MchID                     string   `xml:"mch_id"`                      // 商户号
+	Sign                      string   `xml:"sign"`                        // 签名
+	ContractID                string   `xml:"contract_id"`                 // 委托代扣协议id
+	PlanID                    string   `xml:"plan_id"`                     // 模板id
+	RequestSerial             string   `xml:"request_serial"`              // 请求序列号
+	ContractCode              string   `xml:"contract_code"`               // 签约协议号
+	ContractDisplayAccount    string   `xml:"contract_display_account"`    // 用户账户展示名称
+	ContractState             string   `xml:"contract_state"`              // 协议状态
+	ContractSignedTime        string   `xml:"contract_signed_time"`        // 协议签署时间
+	ContractExpiredTime       string   `xml:"contract_expired_time"`       // 协议到期时间
+	ContractTerminatedTime    string   `xml:"contract_terminated_time"`    // 协议解约时间
+	ContractTerminationMode   string   `xml:"contract_termination_mode"`   // 协议解约方式
+	ContractTerminationRemark string   `xml:"contract_termination_remark"` // 解约备注
+	OpenID                    string   `xml:"openid"`                      // 用户标识
+}
+
+type deleteContractReq struct {
+	XMLName                   xml.Name `xml:"xml"`
+	AppID                     string   `xml:"appid"`                       // 公众账号id
+	MchID                     string   `xml:"mch_id"`                      // 商户号
+	ContractID                string   `xml:"contract_id"`                 // 委托代扣协议id
+	PlanID                    string   `xml:"plan_id"`                     // 模板id
+	ContractCode              string   `xml:"contract_code"`               // 签约协议号
+	ContractTerminationRemark string   `xml:"contract_termination_remark"` // 解约备注
+	Version                   string   `xml:"version"`                     // 版本号
+}
+
+func (req deleteContractReq) URI() string {
+	return "https://api.mch.weixin.qq.com/papay/deletecontract"
+}
+
+// DeleteContractRsp is the response returned by /papay/deletecontract.
+type DeleteContractRsp struct {
+	XMLName      xml.Name `xml:"xml"`
+	ReturnCode   string   `xml:"return_code"`   // 返回状态码
+	ReturnMsg    string   `xml:"return_msg"`    // 返回信息
+	ResultCode   string   `xml:"result_code"`   // 业务结果
+	ErrCode      string   `xml:"err_code"`      // 错误代码
+	ErrCodeDesc  string   `xml:"err_code_des"`  // 错误代码描述
+	AppID        string   `xml:"appid"`         // 公众账号id
+	MchID        string   `xml:"mch_id"`        // 商户号
+	Sign         string   `xml:"sign"`          // 签名
+	ContractID   string   `xml:"contract_id"`   // 委托代扣协议id
+	PlanID       string   `xml:"plan_id"`       // 模板id
+	ContractCode string   `xml:"contract_code"` // 签约协议号
+}
+
+type papPayApplyReq struct {
+	XMLName        xml.Name `xml:"xml"`
+	AppID          string   `xml:"appid"`            // 公众账号id
+	MchID          string   `xml:"mch_id"`           // 商户号
+	NonceStr       string   `xml:"nonce_str"`        // 随机字符串
+	SignType       string   `xml:"sign_type"`        // 签名类型
+	Body           string   `xml:"body"`             // 商品描述
+	Detail         string   `xml:"detail"`           // 商品详情
+	Attach         string   `xml:"attach"`           // 附加数据
+	OutTradeNo     string   `xml:"out_trade_no"`     // 商户订单号
+	TotalFee       string   `xml:"total_fee"`        // 总金额
+	FeeType        string   `xml:"fee_type"`         // 货币类型
+	SpbillCreateIP string   `xml:"spbill_create_ip"` // 终端IP
+	GoodsTag       string   `xml:"goods_tag"`        // 商品标记
+	NotifyURL      string   `xml:"notify_url"`       // 回调通知url
+	TradeType      string   `xml:"trade_type"`       // 交易类型
+	ContractID     string   `xml:"contract_id"`      // 委托代扣协议id
+}
+
+func (req papPayApplyReq) URI() string {
+	return "https://api.mch.weixin.qq.com/pay/pappayapply"
+}
+
+// PapPayApplyRsp is the response returned by /pay/pappayapply.
+type PapPayApplyRsp struct {
+	XMLName     xml.Name `xml:"xml"`
+	ReturnCode  string   `xml:"return_code"`  // 返回状态码
+	ReturnMsg   string   `xml:"return_msg"`   // 返回信息
+	ResultCode  string   `xml:"result_code"`  // 业务结果
+	ErrCode     string   `xml:"err_code"`     // 错误代码
+	ErrCodeDesc string   `xml:"err_code_des"` // 错误代码描述
+	AppID       string   `xml:"appid"`        // 公众账号id
+	MchID       string   `xml:"mch_id"`       // 商户号
+	NonceStr    string   `xml:"nonce_str"`    // 随机字符串
+	Sign        string   `xml:"sign"`         // 签名
+}
+
+type contractOrderReq struct {
+	XMLName                xml.Name `xml:"xml"`
+	AppID                  string   `xml:"appid"`                    // 公众账号id
+	MchID                  string   `xml:"mch_id"`                   // 商户号
+	ContractMchID          string   `xml:"contract_mchid"`           // 签约商户号
+	ContractAppID          string   `xml:"contract_appid"`           // 签约appid
+	DeviceInfo             string   `xml:"device_info"`              // 设备号
+	NonceStr               string   `xml:"nonce_str"`                // 随机字符串
+	SignType               string   `xml:"sign_type"`                // 签名类型
+	Body                   string   `xml:"body"`                     // 商品描述
+	Detail                 string   `xml:"detail"`                   // 商品详情
+	Attach                 string   `xml:"attach"`                   // 附加数据
+	OutTradeNo             string   `xml:"out_trade_no"`             // 商户订单号
+	FeeType                string   `xml:"fee_type"`                 // 货币类型
+	TotalFee               string   `xml:"total_fee"`                // 总金额
+	SpbillCreateIP         string   `xml:"spbill_create_ip"`         // 终端IP
+	TimeStart              string   `xml:"time_start"`               // 交易起始时间
+	TimeExpire             string   `xml:"time_expire"`              // 交易结束时间
+	GoodsTag               string   `xml:"goods_tag"`                // 商品标记
+	NotifyURL              string   `xml:"notify_url"`               // 回调通知url
+	TradeType              string   `xml:"trade_type"`               // 交易类型
+	ProductID              string   `xml:"product_id"`               // 商品ID
+	LimitPay               string   `xml:"limit_pay"`                // 指定支付方式
+	OpenID                 string   `xml:"openid"`                   // 用户标识
+	SceneInfo              string   `xml:"scene_info"`               // 场景信息
+	PlanID                 string   `xml:"plan_id"`                  // 模板id
+	ContractCode           string   `xml:"contract_code"`            // 签约协议号
+	RequestSerial          string   `xml:"request_serial"`           // 请求序列号
+	ContractDisplayAccount string   `xml:"contract_display_account"` // 用户账户展示名称
+	ContractNotifyURL      string   `xml:"contract_notify_url"`      // 签约信息通知url
+}
+
+func (req contractOrderReq) URI() string {
+	return "https://api.mch.weixin.qq.com/pay/contractorder"
+}
+
+// ContractOrderRsp is the response returned by /pay/contractorder.
+type ContractOrderRsp struct {
+	XMLName            xml.Name `xml:"xml"`
+	ReturnCode         string   `xml:"return_code"`           // 返回状态码
+	ReturnMsg          string   `xml:"return_msg"`            // 返回信息
+	ResultCode         string   `xml:"result_code"`           // 业务结果
+	ErrCode            string   `xml:"err_code"`              // 错误代码
+	ErrCodeDesc        string   `xml:"err_code_des"`          // 错误代码描述
+	AppID              string   `xml:"appid"`                 // 公众账号id
+	MchID              string   `xml:"mch_id"`                // 商户号
+	NonceStr           string   `xml:"nonce_str"`             // 随机字符串
+	Sign               string   `xml:"sign"`                  // 签名
+	TradeType          string   `xml:"trade_type"`            // 交易类型
+	PrepayID           string   `xml:"prepay_id"`             // 预支付交易会话标识
+	CodeURL            string   `xml:"code_url"`              // 二维码链接
+	PlanID             string   `xml:"plan_id"`               // 模板id
+	RequestSerial      string   `xml:"request_serial"`        // 请求序列号
+	ContractCode       string   `xml:"contract_code"`         // 签约协议号
+	ContractResultCode string   `xml:"contract_result_code"`  // 预签约结果
+	ContractErrCode    string   `xml:"contract_err_code"`     // 预签约错误代码
+	ContractErrCodeDes string   `xml:"contract_err_code_des"` // 预签约错误描述
+	MwebURL            string   `xml:"mweb_url"`              // 支付跳转链接
+}
+
+// ContractNotifyResult is the result of signing or terminating a contract
+// return from Weixin.
+type ContractNotifyResult struct {
+	ReturnCode              string `xml:"return_code"`               // 返回状态码
+	ReturnMsg               string `xml:"return_msg"`                // 返回信息
+	ResultCode              string `xml:"result_code"`               // 业务结果
+	ErrCode                 string `xml:"err_code"`                  // 错误代码
+	ErrCodeDesc             string `xml:"err_code_des"`              // 错误代码描述
+	MchID                   string `xml:"mch_id"`                    // 商户号
+	ContractCode            string `xml:"contract_code"`             // 签约协议号
+	PlanID                  string `xml:"plan_id"`                   // 模板id
+	OpenID                  string `xml:"openid"`                    // 用户标识
+	Sign                    string `xml:"sign"`                      // 签名
+	ChangeType              string `xml:"change_type"`               // 变更类型
+	OperateTime             string `xml:"operate_time"`              // 操作时间
+	ContractID              string `xml:"contract_id"`               // 委托代扣协议id
+	ContractExpiredTime     string `xml:"contract_expired_time"`     // 协议到期时间
+	ContractTerminationMode string `xml:"contract_termination_mode"` // 协议解约方式
+	RequestSerial           string `xml:"request_serial"`            // 请求序列号
+}
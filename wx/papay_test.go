@@ -0,0 +1,233 @@
+package wx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestEntrustWebURL(t *testing.T) {
+	c := NewClient(Config{AppID: "wx426b3015555a46be", MchID: "1900009851", AppKey: testAppKey})
+	rawURL := c.EntrustWebURL(ContractOptions{
+		PlanID:                 "12535",
+		ContractCode:           "100000",
+		RequestSerial:          1000,
+		ContractDisplayAccount: "微信代扣",
+		NotifyURL:              "https://weixin.qq.com/contract",
+	})
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Scheme+"://"+u.Host+u.Path != entrustWebURI {
+		t.Errorf("returned: %s, expected: %s", rawURL, entrustWebURI)
+	}
+
+	params := map[string]string{}
+	for k := range u.Query() {
+		params[k] = u.Query().Get(k)
+	}
+	if params["version"] != "1.0" || params["request_serial"] != "1000" || params["notify_url"] != "https://weixin.qq.com/contract" {
+		t.Errorf("returned: %v", params)
+	}
+	if sign := signature(params, testAppKey, MD5); params["sign"] != sign {
+		t.Errorf("returned: %s, expected: %s", params["sign"], sign)
+	}
+}
+
+func TestContractNotify(t *testing.T) {
+	params := map[string]string{
+		"return_code":   Success,
+		"return_msg":    "OK",
+		"result_code":   Success,
+		"mch_id":        "1900009851",
+		"contract_code": "100000",
+		"plan_id":       "12535",
+		"openid":        "onqOjjmM1tad-3ROpncN-yUfa6uI",
+		"change_type":   ContractAdd,
+		"operate_time":  "2015-07-01 10:00:00",
+		"contract_id":   "Wx15463511252015071056489715",
+	}
+	c := NewClient(Config{AppKey: testAppKey})
+	req := httptest.NewRequest(http.MethodPost, "/contract", strings.NewReader(signedXML(params, testAppKey, MD5)))
+	result, err := c.ContractNotify(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ChangeType != ContractAdd || result.ContractID != "Wx15463511252015071056489715" {
+		t.Errorf("returned: %#v", result)
+	}
+
+	// the signature of the ADD notification is kept
+	params["change_type"] = ContractDelete
+	req = httptest.NewRequest(http.MethodPost, "/contract", strings.NewReader(toXMLStr(params)))
+	if _, err = c.ContractNotify(req); err == nil {
+		t.Error("expected signature failure with forged notification")
+	}
+}
+
+func TestQueryContract(t *testing.T) {
+	srv := newTestServer(map[string]string{
+		"return_code":    Success,
+		"result_code":    Success,
+		"appid":          "wx426b3015555a46be",
+		"mch_id":         "1900009851",
+		"contract_id":    "Wx15463511252015071056489715",
+		"plan_id":        "12535",
+		"contract_code":  "100000",
+		"contract_state": ContractSigned,
+		"openid":         "onqOjjmM1tad-3ROpncN-yUfa6uI",
+	}, MD5)
+	defer srv.Close()
+
+	target, _ := url.Parse(srv.URL)
+	rewriter := &hostRewriter{target: target}
+	c := NewClient(Config{AppID: "wx426b3015555a46be", MchID: "1900009851", AppKey: testAppKey})
+	c.tlsClient.Transport = rewriter
+
+	rsp, err := c.QueryContract("Wx15463511252015071056489715", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rsp.ContractState != ContractSigned || rsp.OpenID != "onqOjjmM1tad-3ROpncN-yUfa6uI" {
+		t.Errorf("returned: %#v", rsp)
+	}
+
+	if rewriter.paths[0] != "/papay/querycontract" {
+		t.Errorf("returned: %s, expected: /papay/querycontract", rewriter.paths[0])
+	}
+	sent, err := xmlToMap([]byte(rewriter.bodies[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent["contract_id"] != "Wx15463511252015071056489715" || sent["version"] != "1.0" {
+		t.Errorf("returned: %v", sent)
+	}
+}
+
+func TestPapPayApply(t *testing.T) {
+	srv := newTestServer(map[string]string{
+		"return_code": Success,
+		"result_code": Success,
+		"appid":       "wx426b3015555a46be",
+		"mch_id":      "1900009851",
+		"nonce_str":   "5K8264ILTKCH16CQ2502SI8ZNMTM67VS",
+	}, HMACSHA256)
+	defer srv.Close()
+
+	target, _ := url.Parse(srv.URL)
+	rewriter := &hostRewriter{target: target}
+	c := NewClient(Config{
+		AppID:     "wx426b3015555a46be",
+		MchID:     "1900009851",
+		AppKey:    testAppKey,
+		NotifyURL: "https://weixin.qq.com/notify",
+		SignType:  HMACSHA256,
+	})
+	c.tlsClient.Transport = rewriter
+
+	opts := PapPayApplyOptions{
+		Body:       "水电代扣",
+		OutTradeNo: "217752501201407033233368018",
+		TotalFee:   888,
+		ContractID: "Wx15463511252015071056489715",
+	}
+	if _, err := c.PapPayApply(opts); err != nil {
+		t.Fatal(err)
+	}
+
+	if rewriter.paths[0] != "/pay/pappayapply" {
+		t.Errorf("returned: %s, expected: /pay/pappayapply", rewriter.paths[0])
+	}
+	sent, err := xmlToMap([]byte(rewriter.bodies[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent["trade_type"] != "PAP" || sent["contract_id"] != opts.ContractID || sent["total_fee"] != "888" ||
+		sent["notify_url"] != "https://weixin.qq.com/notify" {
+		t.Errorf("returned: %v", sent)
+	}
+	if sent["sign"] != signature(sent, testAppKey, HMACSHA256) {
+		t.Errorf("returned: %v, expected signed with %s", sent, HMACSHA256)
+	}
+
+	opts.ContractID = ""
+	if _, err = c.PapPayApply(opts); err == nil {
+		t.Error("expected failure without contract_id")
+	}
+}
+
+func TestContractOrder(t *testing.T) {
+	srv := newTestServer(map[string]string{
+		"return_code":          Success,
+		"result_code":          Success,
+		"appid":                "wx426b3015555a46be",
+		"mch_id":               "1900009851",
+		"nonce_str":            "5K8264ILTKCH16CQ2502SI8ZNMTM67VS",
+		"contract_result_code": Success,
+		"prepay_id":            "wx201410272009395522657a690389285100",
+		"trade_type":           MWeb,
+	}, MD5)
+	defer srv.Close()
+
+	target, _ := url.Parse(srv.URL)
+	rewriter := &hostRewriter{target: target}
+	c := NewClient(Config{
+		AppID:     "wx426b3015555a46be",
+		MchID:     "1900009851",
+		AppKey:    testAppKey,
+		NotifyURL: "https://weixin.qq.com/notify",
+	})
+	c.tlsClient.Transport = rewriter
+
+	opts := ContractOrderOptions{
+		UnifiedOrderOptions: UnifiedOrderOptions{
+			Body:           "水电代扣",
+			OutTradeNo:     "217752501201407033233368018",
+			TotalFee:       888,
+			SpbillCreateIP: "127.0.0.1",
+			TradeType:      MWeb,
+			SceneInfo:      &SceneInfo{H5Info: &H5Info{Type: "Wap", WapURL: "https://pay.qq.com", WapName: "腾讯充值"}},
+		},
+		Contract: ContractOptions{
+			PlanID:                 "12535",
+			ContractCode:           "100000",
+			RequestSerial:          1000,
+			ContractDisplayAccount: "微信代扣",
+			NotifyURL:              "https://weixin.qq.com/contract",
+		},
+	}
+	if _, err := c.ContractOrder(opts); err != nil {
+		t.Fatal(err)
+	}
+
+	if rewriter.paths[0] != "/pay/contractorder" {
+		t.Errorf("returned: %s, expected: /pay/contractorder", rewriter.paths[0])
+	}
+	sent, err := xmlToMap([]byte(rewriter.bodies[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent["scene_info"] == "" || sent["contract_mchid"] != "1900009851" || sent["plan_id"] != "12535" {
+		t.Errorf("returned: %v", sent)
+	}
+
+	unsupported := []func(*ContractOrderOptions){
+		func(o *ContractOrderOptions) { o.Receipt = true },
+		func(o *ContractOrderOptions) { o.ProfitSharing = true },
+		func(o *ContractOrderOptions) { o.TradeType, o.SubOpenID = JSAPI, "oUpF8uMuAJO_M2pxb1Q9zNjWeS6o" },
+	}
+	for i, modify := range unsupported {
+		o := opts
+		modify(&o)
+		if _, err = c.ContractOrder(o); err == nil {
+			t.Errorf("case %d: expected failure with unsupported parameters", i)
+		}
+	}
+	if len(rewriter.paths) != 1 {
+		t.Errorf("returned: %d requests, expected: 1", len(rewriter.paths))
+	}
+}
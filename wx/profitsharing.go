@@ -0,0 +1,443 @@
+package wx
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+)
+
+// constants for receiver type of profit sharing.
+const (
+	ReceiverMerchantID     = "MERCHANT_ID"       // 商户ID
+	ReceiverPersonalWechat = "PERSONAL_WECHATID" // 个人微信号
+	ReceiverPersonalOpenID = "PERSONAL_OPENID"   // 个人openid
+)
+
+// constants for relation type of profit sharing receiver.
+const (
+	RelationServiceProvider = "SERVICE_PROVIDER" // 服务商
+	RelationStore           = "STORE"            // 门店
+	RelationStaff           = "STAFF"            // 员工
+	RelationStoreOwner      = "STORE_OWNER"      // 店主
+	RelationPartner         = "PARTNER"          // 合作伙伴
+	RelationHeadquarter     = "HEADQUARTER"      // 总部
+	RelationBrand           = "BRAND"            // 品牌方
+	RelationDistributor     = "DISTRIBUTOR"      // 分销商
+	RelationUser            = "USER"             // 用户
+	RelationSupplier        = "SUPPLIER"         // 供应商
+	RelationCustom          = "CUSTOM"           // 自定义
+)
+
+// constants for status of profit sharing.
+const (
+	ProfitSharingAccepted   = "ACCEPTED"   // 受理成功
+	ProfitSharingProcessing = "PROCESSING" // 处理中
+	ProfitSharingFinished   = "FINISHED"   // 处理完成
+	ProfitSharingClosed     = "CLOSED"     // 处理失败，已关单
+)
+
+// ProfitSharingReceiver is a receiver to be added to or removed from the
+// receivers of profit sharing.
+type ProfitSharingReceiver struct {
+	Type           string `json:"type"`                      // 分账接收方类型
+	Account        string `json:"account"`                   // 分账接收方帐号
+	Name           string `json:"name,omitempty"`            // 分账接收方全称
+	RelationType   string `json:"relation_type,omitempty"`   // 与分账方的关系类型
+	CustomRelation string `json:"custom_relation,omitempty"` // 自定义的分账关系
+}
+
+// ProfitSharingAmount is the amount shared to a receiver.
+type ProfitSharingAmount struct {
+	Type        string `json:"type"`           // 分账接收方类型
+	Account     string `json:"account"`        // 分账接收方帐号
+	Amount      int    `json:"amount"`         // 分账金额
+	Description string `json:"description"`    // 分账描述
+	Name        string `json:"name,omitempty"` // 分账个人接收方姓名
+}
+
+// ProfitSharingResult is the result of sharing to a receiver.
+type ProfitSharingResult struct {
+	Type        string `json:"type"`        // 分账接收方类型
+	Account     string `json:"account"`     // 分账接收方帐号
+	Amount      int    `json:"amount"`      // 分账金额
+	Description string `json:"description"` // 分账描述
+	Result      string `json:"result"`      // 分账结果
+	FinishTime  string `json:"finish_time"` // 分账完成时间
+	FailReason  string `json:"fail_reason"` // 分账失败原因
+	DetailID    string `json:"detail_id"`   // 分账明细单号
+}
+
+// AddProfitSharingReceiver adds a receiver of profit sharing. Profit sharing
+// is unavailable in sandbox, so its requests are always sent to production
+// signed with AppKey.
+func (c *Client) AddProfitSharingReceiver(receiver ProfitSharingReceiver) (*ProfitSharingReceiverRsp, error) {
+	return c.doProfitSharingReceiver(true, receiver)
+}
+
+// RemoveProfitSharingReceiver removes a receiver of profit sharing.
+func (c *Client) RemoveProfitSharingReceiver(receiver ProfitSharingReceiver) (*ProfitSharingReceiverRsp, error) {
+	return c.doProfitSharingReceiver(false, receiver)
+}
+
+func (c *Client) doProfitSharingReceiver(add bool, receiver ProfitSharingReceiver) (*ProfitSharingReceiverRsp, error) {
+	data, err := json.Marshal(receiver)
+	if err != nil {
+		return nil, err
+	}
+
+	base := profitSharingReceiverReq{
+		MchID:    c.config.MchID,
+		SubMchID: c.config.SubMchID,
+		AppID:    c.config.AppID,
+		SubAppID: c.config.SubAppID,
+		NonceStr: generateNonceStr(),
+		SignType: HMACSHA256,
+		Receiver: string(data),
+	}
+
+	rsp := &ProfitSharingReceiverRsp{}
+	if add {
+		req := profitSharingAddReceiverReq(base)
+		err = c.doRequest(req.URI(), req, rsp)
+	} else {
+		req := profitSharingRemoveReceiverReq(base)
+		err = c.doRequest(req.URI(), req, rsp)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return rsp, nil
+}
+
+// ProfitSharing shares the profit of a transaction once, after which the
+// remaining amount is unfrozen to the merchant. It requires client
+// certificates.
+func (c *Client) ProfitSharing(transID, outOrderNo string, receivers []ProfitSharingAmount) (*ProfitSharingRsp, error) {
+	return c.doProfitSharing(false, transID, outOrderNo, receivers)
+}
+
+// MultiProfitSharing shares the profit of a transaction, which could be
+// shared again until FinishProfitSharing is called. It requires client
+// certificates.
+func (c *Client) MultiProfitSharing(transID, outOrderNo string, receivers []ProfitSharingAmount) (*ProfitSharingRsp, error) {
+	return c.doProfitSharing(true, transID, outOrderNo, receivers)
+}
+
+func (c *Client) doProfitSharing(multi bool, transID, outOrderNo string, receivers []ProfitSharingAmount) (*ProfitSharingRsp, error) {
+	data, err := json.Marshal(receivers)
+	if err != nil {
+		return nil, err
+	}
+
+	base := profitSharingReq{
+		MchID:         c.config.MchID,
+		SubMchID:      c.config.SubMchID,
+		AppID:         c.config.AppID,
+		SubAppID:      c.config.SubAppID,
+		NonceStr:      generateNonceStr(),
+		SignType:      HMACSHA256,
+		TransactionID: transID,
+		OutOrderNo:    outOrderNo,
+		Receivers:     string(data),
+	}
+
+	rsp := &ProfitSharingRsp{}
+	if multi {
+		req := multiProfitSharingReq(base)
+		err = c.doRequest(req.URI(), req, rsp)
+	} else {
+		err = c.doRequest(base.URI(), base, rsp)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return rsp, nil
+}
+
+// QueryProfitSharing queries the result of profit sharing.
+func (c *Client) QueryProfitSharing(transID, outOrderNo string) (*QueryProfitSharingRsp, error) {
+	req := queryProfitSharingReq{
+		MchID:         c.config.MchID,
+		SubMchID:      c.config.SubMchID,
+		TransactionID: transID,
+		OutOrderNo:    outOrderNo,
+		NonceStr:      generateNonceStr(),
+		SignType:      HMACSHA256,
+	}
+
+	rsp := &QueryProfitSharingRsp{}
+	if err := c.doRequest(req.URI(), req, rsp); err != nil {
+		return nil, err
+	}
+
+	return rsp, nil
+}
+
+// FinishProfitSharing finishes profit sharing of a transaction and unfreezes
+// the remaining amount to the merchant. It requires client certificates.
+func (c *Client) FinishProfitSharing(transID, outOrderNo string, amount int, desc string) (*ProfitSharingRsp, error) {
+	req := finishProfitSharingReq{
+		MchID:         c.config.MchID,
+		SubMchID:      c.config.SubMchID,
+		AppID:         c.config.AppID,
+		SubAppID:      c.config.SubAppID,
+		NonceStr:      generateNonceStr(),
+		SignType:      HMACSHA256,
+		TransactionID: transID,
+		OutOrderNo:    outOrderNo,
+		Amount:        fmt.Sprintf("%d", amount),
+		Description:   desc,
+	}
+
+	rsp := &ProfitSharingRsp{}
+	if err := c.doRequest(req.URI(), req, rsp); err != nil {
+		return nil, err
+	}
+
+	return rsp, nil
+}
+
+// ProfitSharingReturnOptions contains all parameters of
+// /secapi/pay/profitsharingreturn.
+type ProfitSharingReturnOptions struct {
+	OrderID           string // 微信分账单号，与商户分账单号二选一
+	OutOrderNo        string // 商户分账单号，与微信分账单号二选一
+	OutReturnNo       string // 商户回退单号
+	ReturnAccountType string // 回退方类型，目前只支持MERCHANT_ID
+	ReturnAccount     string // 回退方账号
+	ReturnAmount      int    // 回退金额
+	Description       string // 回退描述
+}
+
+// ReturnProfitSharing returns the amount shared to a receiver back to the
+// merchant. It requires client certificates.
+func (c *Client) ReturnProfitSharing(opts ProfitSharingReturnOptions) (*ProfitSharingReturnRsp, error) {
+	accountType := opts.ReturnAccountType
+	if accountType == "" {
+		accountType = ReceiverMerchantID
+	}
+
+	req := profitSharingReturnReq{
+		MchID:             c.config.MchID,
+		SubMchID:          c.config.SubMchID,
+		AppID:             c.config.AppID,
+		SubAppID:          c.config.SubAppID,
+		NonceStr:          generateNonceStr(),
+		SignType:          HMACSHA256,
+		OrderID:           opts.OrderID,
+		OutOrderNo:        opts.OutOrderNo,
+		OutReturnNo:       opts.OutReturnNo,
+		ReturnAccountType: accountType,
+		ReturnAccount:     opts.ReturnAccount,
+		ReturnAmount:      fmt.Sprintf("%d", opts.ReturnAmount),
+		Description:       opts.Description,
+	}
+
+	rsp := &ProfitSharingReturnRsp{}
+	if err := c.doRequest(req.URI(), req, rsp); err != nil {
+		return nil, err
+	}
+
+	return rsp, nil
+}
+
+type profitSharingReceiverReq struct {
+	XMLName  xml.Name `xml:"xml"`
+	MchID    string   `xml:"mch_id"`     // 商户号
+	SubMchID string   `xml:"sub_mch_id"` // 子商户号
+	AppID    string   `xml:"appid"`      // 公众账号ID
+	SubAppID string   `xml:"sub_appid"`  // 子商户公众账号ID
+	NonceStr string   `xml:"nonce_str"`  // 随机字符串
+	SignType string   `xml:"sign_type"`  // 签名类型
+	Receiver string   `xml:"receiver"`   // 分账接收方
+}
+
+type profitSharingAddReceiverReq profitSharingReceiverReq
+
+func (req profitSharingAddReceiverReq) URI() string {
+	return "https://api.mch.weixin.qq.com/pay/profitsharingaddreceiver"
+}
+
+type profitSharingRemoveReceiverReq profitSharingReceiverReq
+
+func (req profitSharingRemoveReceiverReq) URI() string {
+	return "https://api.mch.weixin.qq.com/pay/profitsharingremovereceiver"
+}
+
+// ProfitSharingReceiverRsp is the response returned by
+// /pay/profitsharingaddreceiver and /pay/profitsharingremovereceiver.
+type ProfitSharingReceiverRsp struct {
+	XMLName     xml.Name `xml:"xml"`
+	ReturnCode  string   `xml:"return_code"`  // 返回状态码
+	ReturnMsg   string   `xml:"return_msg"`   // 返回信息
+	ResultCode  string   `xml:"result_code"`  // 业务结果
+	ErrCode     string   `xml:"err_code"`     // 错误代码
+	ErrCodeDesc string   `xml:"err_code_des"` // 错误代码描述
+	MchID       string   `xml:"mch_id"`       // 商户号
+	SubMchID    string   `xml:"sub_mch_id"`   // 子商户号
+	AppID       string   `xml:"appid"`        // 公众账号ID
+	SubAppID    string   `xml:"sub_appid"`    // 子商户公众账号ID
+	NonceStr    string   `xml:"nonce_str"`    // 随机字符串
+	Sign        string   `xml:"sign"`         // 签名
+	Receiver    string   `xml:"receiver"`     // 分账接收方
+}
+
+type profitSharingReq struct {
+	XMLName       xml.Name `xml:"xml"`
+	MchID         string   `xml:"mch_id"`         // 商户号
+	SubMchID      string   `xml:"sub_mch_id"`     // 子商户号
+	AppID         string   `xml:"appid"`          // 公众账号ID
+	SubAppID      string   `xml:"sub_appid"`      // 子商户公众账号ID
+	NonceStr      string   `xml:"nonce_str"`      // 随机字符串
+	SignType      string   `xml:"sign_type"`      // 签名类型
+	TransactionID string   `xml:"transaction_id"` // 微信订单号
+	OutOrderNo    string   `xml:"out_order_no"`   // 商户分账单号
+	Receivers     string   `xml:"receivers"`      // 分账接收方列表
+}
+
+func (req profitSharingReq) URI() string {
+	return "https://api.mch.weixin.qq.com/secapi/pay/profitsharing"
+}
+
+type multiProfitSharingReq profitSharingReq
+
+func (req multiProfitSharingReq) URI() string {
+	return "https://api.mch.weixin.qq.com/secapi/pay/multiprofitsharing"
+}
+
+// ProfitSharingRsp is the response returned by /secapi/pay/profitsharing,
+// /secapi/pay/multiprofitsharing and /secapi/pay/profitsharingfinish.
+type ProfitSharingRsp struct {
+	XMLName       xml.Name `xml:"xml"`
+	ReturnCode    string   `xml:"return_code"`    // 返回状态码
+	ReturnMsg     string   `xml:"return_msg"`     // 返回信息
+	ResultCode    string   `xml:"result_code"`    // 业务结果
+	ErrCode       string   `xml:"err_code"`       // 错误代码
+	ErrCodeDesc   string   `xml:"err_code_des"`   // 错误代码描述
+	MchID         string   `xml:"mch_id"`         // 商户号
+	SubMchID      string   `xml:"sub_mch_id"`     // 子商户号
+	AppID         string   `xml:"appid"`          // 公众账号ID
+	SubAppID      string   `xml:"sub_appid"`      // 子商户公众账号ID
+	NonceStr      string   `xml:"nonce_str"`      // 随机字符串
+	Sign          string   `xml:"sign"`           // 签名
+	TransactionID string   `xml:"transaction_id"` // 微信订单号
+	OutOrderNo    string   `xml:"out_order_no"`   // 商户分账单号
+	OrderID       string   `xml:"order_id"`       // 微信分账单号
+}
+
+type queryProfitSharingReq struct {
+	XMLName       xml.Name `xml:"xml"`
+	MchID         string   `xml:"mch_id"`         // 商户号
+	SubMchID      string   `xml:"sub_mch_id"`     // 子商户号
+	TransactionID string   `xml:"transaction_id"` // 微信订单号
+	OutOrderNo    string   `xml:"out_order_no"`   // 商户分账单号
+	NonceStr      string   `xml:"nonce_str"`      // 随机字符串
+	SignType      string   `xml:"sign_type"`      // 签名类型
+}
+
+func (req queryProfitSharingReq) URI() string {
+	return "https://api.mch.weixin.qq.com/pay/profitsharingquery"
+}
+
+// QueryProfitSharingRsp is the response returned by /pay/profitsharingquery.
+type QueryProfitSharingRsp struct {
+	XMLName       xml.Name `xml:"xml"`
+	ReturnCode    string   `xml:"return_code"`    // 返回状态码
+	ReturnMsg     string   `xml:"return_msg"`     // 返回信息
+	ResultCode    string   `xml:"result_code"`    // 业务结果
+	ErrCode       string   `xml:"err_code"`       // 错误代码
+	ErrCodeDesc   string   `xml:"err_code_des"`   // 错误代码描述
+	MchID         string   `xml:"mch_id"`         // 商户号
+	NonceStr      string   `xml:"nonce_str"`      // 随机字符串
+	Sign          string   `xml:"sign"`           // 签名
+	TransactionID string   `xml:"transaction_id"` // 微信订单号
+	OutOrderNo    string   `xml:"out_order_no"`   // 商户分账单号
+	OrderID       string   `xml:"order_id"`       // 微信分账单号
+	Status        string   `xml:"status"`         // 分账单状态
+	CloseReason   string   `xml:"close_reason"`   // 关单原因
+	Receivers     string   `xml:"receivers"`      // 分账接收方列表
+	Amount        string   `xml:"amount"`         // 分账金额，完结分账时返回
+	Description   string   `xml:"description"`    // 分账描述，完结分账时返回
+}
+
+// Results returns the results decoded from Receivers.
+func (rsp *QueryProfitSharingRsp) Results() ([]ProfitSharingResult, error) {
+	if rsp.Receivers == "" {
+		return nil, nil
+	}
+
+	var results []ProfitSharingResult
+	if err := json.Unmarshal([]byte(rsp.Receivers), &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+type finishProfitSharingReq struct {
+	XMLName       xml.Name `xml:"xml"`
+	MchID         string   `xml:"mch_id"`         // 商户号
+	SubMchID      string   `xml:"sub_mch_id"`     // 子商户号
+	AppID         string   `xml:"appid"`          // 公众账号ID
+	SubAppID      string   `xml:"sub_appid"`      // 子商户公众账号ID
+	NonceStr      string   `xml:"nonce_str"`      // 随机字符串
+	SignType      string   `xml:"sign_type"`      // 签名类型
+	TransactionID string   `xml:"transaction_id"` // 微信订单号
+	OutOrderNo    string   `xml:"out_order_no"`   // 商户分账单号
+	Amount        string   `xml:"amount"`         // 分账金额
+	Description   string   `xml:"description"`    // 分账完结描述
+}
+
+func (req finishProfitSharingReq) URI() string {
+	return "https://api.mch.weixin.qq.com/secapi/pay/profitsharingfinish"
+}
+
+type profitSharingReturnReq struct {
+	XMLName           xml.Name `xml:"xml"`
+	MchID             string   `xml:"mch_id"`              // 商户号
+	SubMchID          string   `xml:"sub_mch_id"`          // 子商户号
+	AppID             string   `xml:"appid"`               // 公众账号ID
+	SubAppID          string   `xml:"sub_appid"`           // 子商户公众账号ID
+	NonceStr          string   `xml:"nonce_str"`           // 随机字符串
+	SignType          string   `xml:"sign_type"`           // 签名类型
+	OrderID           string   `xml:"order_id"`            // 微信分账单号
+	OutOrderNo        string   `xml:"out_order_no"`        // 商户分账单号
+	OutReturnNo       string   `xml:"out_return_no"`       // 商户回退单号
+	ReturnAccountType string   `xml:"return_account_type"` // 回退方类型
+	ReturnAccount     string   `xml:"return_account"`      // 回退方账号
+	ReturnAmount      string   `xml:"return_amount"`       // 回退金额
+	Description       string   `xml:"description"`         // 回退描述
+}
+
+func (req profitSharingReturnReq) URI() string {
+	return "https://api.mch.weixin.qq.com/secapi/pay/profitsharingreturn"
+}
+
+// ProfitSharingReturnRsp is the response returned by
+// /secapi/pay/profitsharingreturn.
+type ProfitSharingReturnRsp struct {
+	XMLName           xml.Name `xml:"xml"`
+	ReturnCode        string   `xml:"return_code"`         // 返回状态码
+	ReturnMsg         string   `xml:"return_msg"`          // 返回信息
+	ResultCode        string   `xml:"result_code"`         // 业务结果
+	ErrCode           string   `xml:"err_code"`            // 错误代码
+	ErrCodeDesc       string   `xml:"err_code_des"`        // 错误代码描述
+	MchID             string   `xml:"mch_id"`              // 商户号
+	SubMchID          string   `xml:"sub_mch_id"`          // 子商户号
+	AppID             string   `xml:"appid"`               // 公众账号ID
+	SubAppID          string   `xml:"sub_appid"`           // 子商户公众账号ID
+	NonceStr          string   `xml:"nonce_str"`           // 随机字符串
+	Sign              string   `xml:"sign"`                // 签名
+	OrderID           string   `xml:"order_id"`            // 微信分账单号
+	OutOrderNo        string   `xml:"out_order_no"`        // 商户分账单号
+	OutReturnNo       string   `xml:"out_return_no"`       // 商户回退单号
+	ReturnNo          string   `xml:"return_no"`           // 微信回退单号
+	ReturnAccountType string   `xml:"return_account_type"` // 回退方类型
+	ReturnAccount     string   `xml:"return_account"`      // 回退方账号
+	ReturnAmount      string   `xml:"return_amount"`       // 回退金额
+	Description       string   `xml:"description"`         // 回退描述
+	Result            string   `xml:"result"`              // 回退结果
+	FailReason        string   `xml:"fail_reason"`         // 失败原因
+	FinishTime        string   `xml:"finish_time"`         // 完成时间
+}
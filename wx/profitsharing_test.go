@@ -0,0 +1,115 @@
+package wx
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestProfitSharing(t *testing.T) {
+	srv := newTestServer(map[string]string{
+		"return_code":    Success,
+		"result_code":    Success,
+		"mch_id":         "10000100",
+		"appid":          "wx2421b1c4370ec43b",
+		"nonce_str":      "6f6a1ff6a5c8a4d8",
+		"transaction_id": "4208450740201411110007820472",
+		"out_order_no":   "P20150806125346",
+		"order_id":       "3008450740201411110007820472",
+	}, HMACSHA256)
+	defer srv.Close()
+
+	target, _ := url.Parse(srv.URL)
+	rewriter := &hostRewriter{target: target}
+	c := NewClient(Config{AppID: "wx2421b1c4370ec43b", MchID: "10000100", AppKey: testAppKey})
+	c.tlsClient.Transport = rewriter
+
+	receivers := []ProfitSharingAmount{{
+		Type:        ReceiverMerchantID,
+		Account:     "190001001",
+		Amount:      100,
+		Description: "分到商户",
+	}}
+	rsp, err := c.ProfitSharing("4208450740201411110007820472", "P20150806125346", receivers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rsp.OrderID != "3008450740201411110007820472" {
+		t.Errorf("returned: %s, expected: %s", rsp.OrderID, "3008450740201411110007820472")
+	}
+
+	if _, err = c.MultiProfitSharing("4208450740201411110007820472", "P20150806125347", receivers); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "/secapi/pay/profitsharing,/secapi/pay/multiprofitsharing"
+	if strings.Join(rewriter.paths, ",") != expected {
+		t.Errorf("returned: %v, expected: %s", rewriter.paths, expected)
+	}
+
+	sent, err := xmlToMap([]byte(rewriter.bodies[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent["sign_type"] != HMACSHA256 || sent["sign"] != signature(sent, testAppKey, HMACSHA256) {
+		t.Errorf("returned: %v, expected signed with %s", sent, HMACSHA256)
+	}
+	var sentReceivers []ProfitSharingAmount
+	if err = json.Unmarshal([]byte(sent["receivers"]), &sentReceivers); err != nil {
+		t.Fatal(err)
+	}
+	if len(sentReceivers) != 1 || sentReceivers[0] != receivers[0] {
+		t.Errorf("returned: %v, expected: %v", sentReceivers, receivers)
+	}
+}
+
+func TestProfitSharingSignatureFailure(t *testing.T) {
+	srv := newTestServer(map[string]string{
+		"return_code": Success,
+		"result_code": Success,
+		"mch_id":      "10000100",
+		"order_id":    "3008450740201411110007820472",
+	}, MD5)
+	defer srv.Close()
+
+	target, _ := url.Parse(srv.URL)
+	c := NewClient(Config{AppKey: testAppKey})
+	c.tlsClient.Transport = &hostRewriter{target: target}
+
+	if _, err := c.QueryProfitSharing("4208450740201411110007820472", "P20150806125346"); err == nil {
+		t.Error("expected signature failure with response signed by MD5")
+	}
+}
+
+func TestProfitSharingSandBox(t *testing.T) {
+	srv := newTestServer(map[string]string{
+		"return_code": Success,
+		"result_code": Success,
+		"mch_id":      "10000100",
+	}, HMACSHA256)
+	defer srv.Close()
+
+	// profit sharing is unavailable in sandbox, so it is sent to production
+	// signed with AppKey rather than the sandbox sign key
+	target, _ := url.Parse(srv.URL)
+	rewriter := &hostRewriter{target: target}
+	c := NewClient(Config{MchID: "10000100", AppKey: testAppKey, SandBox: true})
+	c.tlsClient.Transport = rewriter
+
+	receiver := ProfitSharingReceiver{Type: ReceiverMerchantID, Account: "190001001", RelationType: RelationPartner}
+	if _, err := c.AddProfitSharingReceiver(receiver); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.RemoveProfitSharingReceiver(receiver); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.FinishProfitSharing("4208450740201411110007820472", "P20150806125346", 0, "分账完结"); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "/pay/profitsharingaddreceiver,/pay/profitsharingremovereceiver,/secapi/pay/profitsharingfinish"
+	if strings.Join(rewriter.paths, ",") != expected {
+		t.Errorf("returned: %v, expected: %s", rewriter.paths, expected)
+	}
+}
@@ -0,0 +1,283 @@
+package wx
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// constants for scene of red packets.
+const (
+	ScenePromotion    = "PRODUCT_1" // 商品促销
+	SceneLottery      = "PRODUCT_2" // 抽奖
+	SceneVirtualPrize = "PRODUCT_3" // 虚拟物品兑奖
+	SceneCorporate    = "PRODUCT_4" // 企业内部福利
+	SceneChannel      = "PRODUCT_5" // 渠道分润
+	SceneInsurance    = "PRODUCT_6" // 保险回馈
+	SceneLotteryPrize = "PRODUCT_7" // 彩票派奖
+	SceneTaxLottery   = "PRODUCT_8" // 税务刮奖
+)
+
+// RedPackOptions contains all parameters of /mmpaymkttransfers/sendredpack
+// and /mmpaymkttransfers/sendgroupredpack.
+type RedPackOptions struct {
+	MchBillNo   string // 商户订单号
+	SendName    string // 商户名称
+	ReOpenID    string // 用户openid，裂变红包为种子用户
+	TotalAmount int    // 付款金额
+	TotalNum    int    // 红包发放总人数，普通红包为1，裂变红包为3到20
+	Wishing     string // 红包祝福语
+	ClientIP    string // Ip地址，普通红包必填，裂变红包须为空
+	ActName     string // 活动名称
+	Remark      string // 备注
+	SceneID     string // 场景id，可选
+	RiskInfo    string // 活动信息，可选
+}
+
+// limits of the number of users sharing a group red packet.
+const (
+	minGroupRedPackNum = 3
+	maxGroupRedPackNum = 20
+)
+
+// validate checks opts for a red packet, or a group red packet if group.
+func (opts RedPackOptions) validate(group bool) error {
+	if opts.MchBillNo == "" || len(opts.MchBillNo) > 28 {
+		return fmt.Errorf("invalid mch_billno %q, 1 to 28 bytes required", opts.MchBillNo)
+	}
+
+	if opts.ReOpenID == "" {
+		return errors.New("re_openid required")
+	}
+
+	if opts.TotalAmount <= 0 {
+		return fmt.Errorf("invalid total_amount %d", opts.TotalAmount)
+	}
+
+	lengths := []struct {
+		name  string
+		value string
+		max   int
+	}{
+		{"send_name", opts.SendName, 32},
+		{"wishing", opts.Wishing, 128},
+		{"act_name", opts.ActName, 32},
+		{"remark", opts.Remark, 256},
+	}
+	for _, l := range lengths {
+		if l.value == "" || len(l.value) > l.max {
+			return fmt.Errorf("invalid %s %q, 1 to %d bytes required", l.name, l.value, l.max)
+		}
+	}
+
+	if group {
+		if opts.TotalNum < minGroupRedPackNum || opts.TotalNum > maxGroupRedPackNum {
+			return fmt.Errorf("invalid total_num %d of group red packet, %d to %d required",
+				opts.TotalNum, minGroupRedPackNum, maxGroupRedPackNum)
+		}
+		if opts.ClientIP != "" {
+			return errors.New("client_ip not supported by group red packet")
+		}
+		return nil
+	}
+
+	if opts.TotalNum != 1 {
+		return fmt.Errorf("invalid total_num %d of red packet, 1 required", opts.TotalNum)
+	}
+	if net.ParseIP(opts.ClientIP) == nil {
+		return fmt.Errorf("invalid client_ip %q", opts.ClientIP)
+	}
+	return nil
+}
+
+// SendRedPack sends a cash red packet to a user. It requires client
+// certificates.
+func (c *Client) SendRedPack(opts RedPackOptions) (*SendRedPackRsp, error) {
+	if err := opts.validate(false); err != nil {
+		return nil, err
+	}
+
+	req := sendRedPackReq{
+		NonceStr:    generateNonceStr(),
+		MchBillNo:   opts.MchBillNo,
+		MchID:       c.config.MchID,
+		WxAppID:     c.config.AppID,
+		SendName:    opts.SendName,
+		ReOpenID:    opts.ReOpenID,
+		TotalAmount: fmt.Sprintf("%d", opts.TotalAmount),
+		TotalNum:    fmt.Sprintf("%d", opts.TotalNum),
+		Wishing:     opts.Wishing,
+		ClientIP:    opts.ClientIP,
+		ActName:     opts.ActName,
+		Remark:      opts.Remark,
+		SceneID:     opts.SceneID,
+		RiskInfo:    opts.RiskInfo,
+	}
+
+	rsp := &SendRedPackRsp{}
+	if _, _, err := c.post(req.URI(), req, rsp); err != nil {
+		return nil, err
+	}
+
+	return rsp, nil
+}
+
+// SendGroupRedPack sends a group red packet to a seed user, which will be
+// shared among TotalNum users randomly. ClientIP must be empty. It requires
+// client certificates.
+func (c *Client) SendGroupRedPack(opts RedPackOptions) (*SendRedPackRsp, error) {
+	if err := opts.validate(true); err != nil {
+		return nil, err
+	}
+
+	req := sendGroupRedPackReq{
+		NonceStr:    generateNonceStr(),
+		MchBillNo:   opts.MchBillNo,
+		MchID:       c.config.MchID,
+		WxAppID:     c.config.AppID,
+		SendName:    opts.SendName,
+		ReOpenID:    opts.ReOpenID,
+		TotalAmount: fmt.Sprintf("%d", opts.TotalAmount),
+		TotalNum:    fmt.Sprintf("%d", opts.TotalNum),
+		AmtType:     "ALL_RAND",
+		Wishing:     opts.Wishing,
+		ActName:     opts.ActName,
+		Remark:      opts.Remark,
+		SceneID:     opts.SceneID,
+		RiskInfo:    opts.RiskInfo,
+	}
+
+	rsp := &SendRedPackRsp{}
+	if _, _, err := c.post(req.URI(), req, rsp); err != nil {
+		return nil, err
+	}
+
+	return rsp, nil
+}
+
+// QueryRedPack queries a red packet by mchBillNo. It requires client
+// certificates.
+func (c *Client) QueryRedPack(mchBillNo string) (*QueryRedPackRsp, error) {
+	req := queryRedPackReq{
+		NonceStr:  generateNonceStr(),
+		MchBillNo: mchBillNo,
+		MchID:     c.config.MchID,
+		AppID:     c.config.AppID,
+		BillType:  "MCHT",
+	}
+
+	rsp := &QueryRedPackRsp{}
+	if _, _, err := c.post(req.URI(), req, rsp); err != nil {
+		return nil, err
+	}
+
+	return rsp, nil
+}
+
+type sendRedPackReq struct {
+	XMLName     xml.Name `xml:"xml"`
+	NonceStr    string   `xml:"nonce_str"`    // 随机字符串
+	MchBillNo   string   `xml:"mch_billno"`   // 商户订单号
+	MchID       string   `xml:"mch_id"`       // 商户号
+	WxAppID     string   `xml:"wxappid"`      // 公众账号appid
+	SendName    string   `xml:"send_name"`    // 商户名称
+	ReOpenID    string   `xml:"re_openid"`    // 用户openid
+	TotalAmount string   `xml:"total_amount"` // 付款金额
+	TotalNum    string   `xml:"total_num"`    // 红包发放总人数
+	Wishing     string   `xml:"wishing"`      // 红包祝福语
+	ClientIP    string   `xml:"client_ip"`    // Ip地址
+	ActName     string   `xml:"act_name"`     // 活动名称
+	Remark      string   `xml:"remark"`       // 备注
+	SceneID     string   `xml:"scene_id"`     // 场景id
+	RiskInfo    string   `xml:"risk_info"`    // 活动信息
+}
+
+func (req sendRedPackReq) URI() string {
+	return "https://api.mch.weixin.qq.com/mmpaymkttransfers/sendredpack"
+}
+
+type sendGroupRedPackReq struct {
+	XMLName     xml.Name `xml:"xml"`
+	NonceStr    string   `xml:"nonce_str"`    // 随机字符串
+	MchBillNo   string   `xml:"mch_billno"`   // 商户订单号
+	MchID       string   `xml:"mch_id"`       // 商户号
+	WxAppID     string   `xml:"wxappid"`      // 公众账号appid
+	SendName    string   `xml:"send_name"`    // 商户名称
+	ReOpenID    string   `xml:"re_openid"`    // 种子用户openid
+	TotalAmount string   `xml:"total_amount"` // 总金额
+	TotalNum    string   `xml:"total_num"`    // 红包发放总人数
+	AmtType     string   `xml:"amt_type"`     // 红包金额设置方式
+	Wishing     string   `xml:"wishing"`      // 红包祝福语
+	ActName     string   `xml:"act_name"`     // 活动名称
+	Remark      string   `xml:"remark"`       // 备注
+	SceneID     string   `xml:"scene_id"`     // 场景id
+	RiskInfo    string   `xml:"risk_info"`    // 活动信息
+}
+
+func (req sendGroupRedPackReq) URI() string {
+	return "https://api.mch.weixin.qq.com/mmpaymkttransfers/sendgroupredpack"
+}
+
+// SendRedPackRsp is the response returned by /mmpaymkttransfers/sendredpack
+// and /mmpaymkttransfers/sendgroupredpack.
+type SendRedPackRsp struct {
+	XMLName     xml.Name `xml:"xml"`
+	ReturnCode  string   `xml:"return_code"`  // 返回状态码
+	ReturnMsg   string   `xml:"return_msg"`   // 返回信息
+	ResultCode  string   `xml:"result_code"`  // 业务结果
+	ErrCode     string   `xml:"err_code"`     // 错误代码
+	ErrCodeDesc string   `xml:"err_code_des"` // 错误代码描述
+	MchBillNo   string   `xml:"mch_billno"`   // 商户订单号
+	MchID       string   `xml:"mch_id"`       // 商户号
+	WxAppID     string   `xml:"wxappid"`      // 公众账号appid
+	ReOpenID    string   `xml:"re_openid"`    // 用户openid
+	TotalAmount string   `xml:"total_amount"` // 付款金额
+	SendListID  string   `xml:"send_listid"`  // 微信单号
+}
+
+type queryRedPackReq struct {
+	XMLName   xml.Name `xml:"xml"`
+	NonceStr  string   `xml:"nonce_str"`  // 随机字符串
+	MchBillNo string   `xml:"mch_billno"` // 商户订单号
+	MchID     string   `xml:"mch_id"`     // 商户号
+	AppID     string   `xml:"appid"`      // Appid
+	BillType  string   `xml:"bill_type"`  // 订单类型
+}
+
+func (req queryRedPackReq) URI() string {
+	return "https://api.mch.weixin.qq.com/mmpaymkttransfers/gethbinfo"
+}
+
+// QueryRedPackRsp is the response returned by /mmpaymkttransfers/gethbinfo.
+type QueryRedPackRsp struct {
+	XMLName      xml.Name      `xml:"xml"`
+	ReturnCode   string        `xml:"return_code"`   // 返回状态码
+	ReturnMsg    string        `xml:"return_msg"`    // 返回信息
+	ResultCode   string        `xml:"result_code"`   // 业务结果
+	ErrCode      string        `xml:"err_code"`      // 错误代码
+	ErrCodeDesc  string        `xml:"err_code_des"`  // 错误代码描述
+	MchBillNo    string        `xml:"mch_billno"`    // 商户订单号
+	MchID        string        `xml:"mch_id"`        // 商户号
+	DetailID     string        `xml:"detail_id"`     // 红包单号
+	Status       string        `xml:"status"`        // 红包状态
+	SendType     string        `xml:"send_type"`     // 发放类型
+	HbType       string        `xml:"hb_type"`       // 红包类型
+	TotalNum     string        `xml:"total_num"`     // 红包个数
+	TotalAmount  string        `xml:"total_amount"`  // 红包总金额
+	Reason       string        `xml:"reason"`        // 失败原因
+	SendTime     string        `xml:"send_time"`     // 红包发送时间
+	RefundTime   string        `xml:"refund_time"`   // 红包退款时间
+	RefundAmount string        `xml:"refund_amount"` // 红包退款金额
+	Wishing      string        `xml:"wishing"`       // 祝福语
+	Remark       string        `xml:"remark"`        // 活动描述
+	ActName      string        `xml:"act_name"`      // 活动名称
+	HbList       []RedPackInfo `xml:"hblist>hbinfo"` // 裂变红包领取列表
+}
+
+// RedPackInfo is a red packet received by a user.
+type RedPackInfo struct {
+	OpenID  string `xml:"openid"`   // 领取红包的openid
+	Amount  string `xml:"amount"`   // 领取金额
+	RcvTime string `xml:"rcv_time"` // 领取红包的时间
+}
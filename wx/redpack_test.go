@@ -0,0 +1,163 @@
+package wx
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestSendRedPack(t *testing.T) {
+	srv := newTestServer(map[string]string{
+		"return_code":  Success,
+		"result_code":  Success,
+		"mch_billno":   "0010010404201411170000046545",
+		"mch_id":       "10000098",
+		"wxappid":      "wx8888888888888888",
+		"re_openid":    "oxTWIuGaIt6gTKsQRLau2M0yL16E",
+		"total_amount": "1000",
+		"send_listid":  "100000000020150520314766074200",
+	}, MD5)
+	defer srv.Close()
+
+	target, _ := url.Parse(srv.URL)
+	rewriter := &hostRewriter{target: target}
+	c := NewClient(Config{AppID: "wx8888888888888888", MchID: "10000098", AppKey: testAppKey})
+	c.tlsClient.Transport = rewriter
+
+	opts := RedPackOptions{
+		MchBillNo:   "0010010404201411170000046545",
+		SendName:    "天虹百货",
+		ReOpenID:    "oxTWIuGaIt6gTKsQRLau2M0yL16E",
+		TotalAmount: 1000,
+		TotalNum:    1,
+		Wishing:     "感谢您参加猜灯谜活动，祝您元宵节快乐！",
+		ClientIP:    "192.168.0.1",
+		ActName:     "猜灯谜抢红包活动",
+		Remark:      "猜越多得越多，快来抢！",
+	}
+	rsp, err := c.SendRedPack(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rsp.SendListID != "100000000020150520314766074200" {
+		t.Errorf("returned: %s, expected: %s", rsp.SendListID, "100000000020150520314766074200")
+	}
+
+	if rewriter.paths[0] != "/mmpaymkttransfers/sendredpack" {
+		t.Errorf("returned: %s, expected: /mmpaymkttransfers/sendredpack", rewriter.paths[0])
+	}
+	sent, err := xmlToMap([]byte(rewriter.bodies[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent["wxappid"] != "wx8888888888888888" || sent["mch_billno"] != opts.MchBillNo ||
+		sent["re_openid"] != opts.ReOpenID || sent["client_ip"] != "192.168.0.1" {
+		t.Errorf("returned: %v", sent)
+	}
+
+	invalid := []func(*RedPackOptions){
+		func(o *RedPackOptions) { o.TotalNum = 3 },
+		func(o *RedPackOptions) { o.ClientIP = "" },
+		func(o *RedPackOptions) { o.ReOpenID = "" },
+		func(o *RedPackOptions) { o.Wishing = "" },
+	}
+	for i, modify := range invalid {
+		o := opts
+		modify(&o)
+		if _, err = c.SendRedPack(o); err == nil {
+			t.Errorf("case %d: expected validation error", i)
+		}
+	}
+	if len(rewriter.paths) != 1 {
+		t.Errorf("returned: %d requests, expected: 1", len(rewriter.paths))
+	}
+}
+
+func TestSendGroupRedPack(t *testing.T) {
+	srv := newTestServer(map[string]string{
+		"return_code": Success,
+		"result_code": Success,
+		"mch_billno":  "0010010404201411170000046546",
+	}, MD5)
+	defer srv.Close()
+
+	target, _ := url.Parse(srv.URL)
+	rewriter := &hostRewriter{target: target}
+	c := NewClient(Config{AppID: "wx8888888888888888", MchID: "10000098", AppKey: testAppKey})
+	c.tlsClient.Transport = rewriter
+
+	opts := RedPackOptions{
+		MchBillNo:   "0010010404201411170000046546",
+		SendName:    "天虹百货",
+		ReOpenID:    "oxTWIuGaIt6gTKsQRLau2M0yL16E",
+		TotalAmount: 1000,
+		TotalNum:    3,
+		Wishing:     "感谢您参加猜灯谜活动，祝您元宵节快乐！",
+		ActName:     "猜灯谜抢红包活动",
+		Remark:      "猜越多得越多，快来抢！",
+	}
+	if _, err := c.SendGroupRedPack(opts); err != nil {
+		t.Fatal(err)
+	}
+
+	if rewriter.paths[0] != "/mmpaymkttransfers/sendgroupredpack" {
+		t.Errorf("returned: %s, expected: /mmpaymkttransfers/sendgroupredpack", rewriter.paths[0])
+	}
+	sent, err := xmlToMap([]byte(rewriter.bodies[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent["wxappid"] != "wx8888888888888888" || sent["mch_billno"] != opts.MchBillNo ||
+		sent["re_openid"] != opts.ReOpenID || sent["total_num"] != "3" || sent["amt_type"] != "ALL_RAND" {
+		t.Errorf("returned: %v", sent)
+	}
+
+	opts.ClientIP = "192.168.0.1"
+	if _, err = c.SendGroupRedPack(opts); err == nil {
+		t.Error("expected failure with client_ip")
+	}
+	opts.ClientIP = ""
+	opts.TotalNum = 1
+	if _, err = c.SendGroupRedPack(opts); err == nil {
+		t.Error("expected failure with total_num 1")
+	}
+}
+
+func TestQueryRedPack(t *testing.T) {
+	srv := newTestServer(map[string]string{
+		"return_code":  Success,
+		"result_code":  Success,
+		"mch_billno":   "0010010404201411170000046545",
+		"mch_id":       "10000098",
+		"detail_id":    "10000417012016080830956240040",
+		"status":       "RECEIVED",
+		"send_type":    "API",
+		"hb_type":      "NORMAL",
+		"total_num":    "1",
+		"total_amount": "100",
+	}, MD5)
+	defer srv.Close()
+
+	target, _ := url.Parse(srv.URL)
+	rewriter := &hostRewriter{target: target}
+	c := NewClient(Config{AppID: "wx8888888888888888", MchID: "10000098", AppKey: testAppKey})
+	c.tlsClient.Transport = rewriter
+
+	rsp, err := c.QueryRedPack("0010010404201411170000046545")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rsp.Status != "RECEIVED" || rsp.DetailID != "10000417012016080830956240040" {
+		t.Errorf("returned: %#v", rsp)
+	}
+
+	if rewriter.paths[0] != "/mmpaymkttransfers/gethbinfo" {
+		t.Errorf("returned: %s, expected: /mmpaymkttransfers/gethbinfo", rewriter.paths[0])
+	}
+	sent, err := xmlToMap([]byte(rewriter.bodies[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent["appid"] != "wx8888888888888888" || sent["mch_billno"] != "0010010404201411170000046545" || sent["bill_type"] != "MCHT" {
+		t.Errorf("returned: %v", sent)
+	}
+}
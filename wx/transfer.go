@@ -0,0 +1,371 @@
+package wx
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"encoding/xml"
+	"errors"
+	"fmt"
+)
+
+// constants for check name option of transfers.
+const (
+	NoCheck    = "NO_CHECK"    // 不校验真实姓名
+	ForceCheck = "FORCE_CHECK" // 强校验真实姓名
+)
+
+// TransferOptions contains all parameters of /mmpaymkttransfers/promotion/transfers.
+type TransferOptions struct {
+	DeviceInfo     string // 设备号，可选
+	PartnerTradeNo string // 商户订单号
+	OpenID         string // 用户openid
+	CheckName      string // 校验用户姓名选项
+	ReUserName     string // 收款用户姓名，CheckName为ForceCheck时必填
+	Amount         int    // 金额
+	Desc           string // 企业付款备注
+	SpbillCreateIP string // Ip地址
+}
+
+// Transfer pays to the balance of a Weixin user. It requires client
+// certificates. On SYSTEMERROR the transfer must be retried with the same
+// PartnerTradeNo.
+func (c *Client) Transfer(opts TransferOptions) (*TransferRsp, error) {
+	checkName := opts.CheckName
+	if checkName == "" {
+		checkName = NoCheck
+	}
+
+	req := transferReq{
+		MchAppID:       c.config.AppID,
+		MchID:          c.config.MchID,
+		DeviceInfo:     opts.DeviceInfo,
+		NonceStr:       generateNonceStr(),
+		PartnerTradeNo: opts.PartnerTradeNo,
+		OpenID:         opts.OpenID,
+		CheckName:      checkName,
+		ReUserName:     opts.ReUserName,
+		Amount:         fmt.Sprintf("%d", opts.Amount),
+		Desc:           opts.Desc,
+		SpbillCreateIP: opts.SpbillCreateIP,
+	}
+
+	rsp := &TransferRsp{}
+	if _, _, err := c.post(req.URI(), req, rsp); err != nil {
+		return nil, err
+	}
+
+	return rsp, nil
+}
+
+// QueryTransfer queries a transfer to balance by partnerTradeNo.
+func (c *Client) QueryTransfer(partnerTradeNo string) (*QueryTransferRsp, error) {
+	req := queryTransferReq{
+		AppID:          c.config.AppID,
+		MchID:          c.config.MchID,
+		NonceStr:       generateNonceStr(),
+		PartnerTradeNo: partnerTradeNo,
+	}
+
+	rsp := &QueryTransferRsp{}
+	if _, _, err := c.post(req.URI(), req, rsp); err != nil {
+		return nil, err
+	}
+
+	return rsp, nil
+}
+
+// PayBankOptions contains all parameters of /mmpaysptrans/pay_bank.
+type PayBankOptions struct {
+	PartnerTradeNo string // 商户企业付款单号
+	BankNo         string // 收款方银行卡号，明文
+	TrueName       string // 收款方用户名，明文
+	BankCode       string // 收款方开户行
+	Amount         int    // 付款金额
+	Desc           string // 付款说明，可选
+}
+
+// PayBank pays to a bank card. The card number and the name of the payee are
+// encrypted with the RSA public key fetched from Weixin. It requires client
+// certificates.
+func (c *Client) PayBank(opts PayBankOptions) (*PayBankRsp, error) {
+	pub, err := c.bankPublicKey()
+	if err != nil {
+		return nil, err
+	}
+
+	encBankNo, err := encryptOAEP(pub, opts.BankNo)
+	if err != nil {
+		return nil, err
+	}
+
+	encTrueName, err := encryptOAEP(pub, opts.TrueName)
+	if err != nil {
+		return nil, err
+	}
+
+	req := payBankReq{
+		MchID:          c.config.MchID,
+		PartnerTradeNo: opts.PartnerTradeNo,
+		NonceStr:       generateNonceStr(),
+		EncBankNo:      encBankNo,
+		EncTrueName:    encTrueName,
+		BankCode:       opts.BankCode,
+		Amount:         fmt.Sprintf("%d", opts.Amount),
+		Desc:           opts.Desc,
+	}
+
+	rsp := &PayBankRsp{}
+	if err := c.doRequest(req.URI(), req, rsp); err != nil {
+		return nil, err
+	}
+
+	return rsp, nil
+}
+
+// QueryBank queries a payment to bank card by partnerTradeNo.
+func (c *Client) QueryBank(partnerTradeNo string) (*QueryBankRsp, error) {
+	req := queryBankReq{
+		MchID:          c.config.MchID,
+		PartnerTradeNo: partnerTradeNo,
+		NonceStr:       generateNonceStr(),
+	}
+
+	rsp := &QueryBankRsp{}
+	if _, _, err := c.post(req.URI(), req, rsp); err != nil {
+		return nil, err
+	}
+
+	return rsp, nil
+}
+
+// GetPublicKey gets the RSA public key used to encrypt bank card info.
+func (c *Client) GetPublicKey() (*GetPublicKeyRsp, error) {
+	req := getPublicKeyReq{
+		MchID:    c.config.MchID,
+		NonceStr: generateNonceStr(),
+		SignType: MD5,
+	}
+
+	rsp := &GetPublicKeyRsp{}
+	if _, _, err := c.post(req.URI(), req, rsp); err != nil {
+		return nil, err
+	}
+
+	return rsp, nil
+}
+
+// bankPublicKey returns the cached RSA public key, fetching it on first use.
+func (c *Client) bankPublicKey() (*rsa.PublicKey, error) {
+	c.cache.mu.Lock()
+	defer c.cache.mu.Unlock()
+
+	if c.cache.pubKey != nil {
+		return c.cache.pubKey, nil
+	}
+
+	rsp, err := c.GetPublicKey()
+	if err != nil {
+		return nil, err
+	}
+
+	pub, err := parseRSAPublicKey([]byte(rsp.PubKey))
+	if err != nil {
+		return nil, err
+	}
+
+	c.cache.pubKey = pub
+	return pub, nil
+}
+
+func parseRSAPublicKey(data []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("failed to decode public key")
+	}
+
+	if pub, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return pub, nil
+	}
+
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	rsaPub, ok := pub.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("need a RSA public key, got %T", pub)
+	}
+	return rsaPub, nil
+}
+
+// encryptOAEP encrypts plain with RSA-OAEP (SHA-1) and encodes it in base64.
+func encryptOAEP(pub *rsa.PublicKey, plain string) (string, error) {
+	data, err := rsa.EncryptOAEP(sha1.New(), rand.Reader, pub, []byte(plain), nil)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+type transferReq struct {
+	XMLName        xml.Name `xml:"xml"`
+	MchAppID       string   `xml:"mch_appid"`        // 商户账号appid
+	MchID          string   `xml:"mchid"`            // 商户号
+	DeviceInfo     string   `xml:"device_info"`      // 设备号
+	NonceStr       string   `xml:"nonce_str"`        // 随机字符串
+	PartnerTradeNo string   `xml:"partner_trade_no"` // 商户订单号
+	OpenID         string   `xml:"openid"`           // 用户openid
+	CheckName      string   `xml:"check_name"`       // 校验用户姓名选项
+	ReUserName     string   `xml:"re_user_name"`     // 收款用户姓名
+	Amount         string   `xml:"amount"`           // 金额
+	Desc           string   `xml:"desc"`             // 企业付款备注
+	SpbillCreateIP string   `xml:"spbill_create_ip"` // Ip地址
+}
+
+func (req transferReq) URI() string {
+	return "https://api.mch.weixin.qq.com/mmpaymkttransfers/promotion/transfers"
+}
+
+// TransferRsp is the response returned by /mmpaymkttransfers/promotion/transfers.
+type TransferRsp struct {
+	XMLName        xml.Name `xml:"xml"`
+	ReturnCode     string   `xml:"return_code"`      // 返回状态码
+	ReturnMsg      string   `xml:"return_msg"`       // 返回信息
+	MchAppID       string   `xml:"mch_appid"`        // 商户appid
+	MchID          string   `xml:"mchid"`            // 商户号
+	DeviceInfo     string   `xml:"device_info"`      // 设备号
+	NonceStr       string   `xml:"nonce_str"`        // 随机字符串
+	ResultCode     string   `xml:"result_code"`      // 业务结果
+	ErrCode        string   `xml:"err_code"`         // 错误代码
+	ErrCodeDesc    string   `xml:"err_code_des"`     // 错误代码描述
+	PartnerTradeNo string   `xml:"partner_trade_no"` // 商户订单号
+	PaymentNo      string   `xml:"payment_no"`       // 微信付款单号
+	PaymentTime    string   `xml:"payment_time"`     // 付款成功时间
+}
+
+type queryTransferReq struct {
+	XMLName        xml.Name `xml:"xml"`
+	NonceStr       string   `xml:"nonce_str"`        // 随机字符串
+	PartnerTradeNo string   `xml:"partner_trade_no"` // 商户订单号
+	MchID          string   `xml:"mch_id"`           // 商户号
+	AppID          string   `xml:"appid"`            // Appid
+}
+
+func (req queryTransferReq) URI() string {
+	return "https://api.mch.weixin.qq.com/mmpaymkttransfers/gettransferinfo"
+}
+
+// QueryTransferRsp is the response returned by /mmpaymkttransfers/gettransferinfo.
+type QueryTransferRsp struct {
+	XMLName        xml.Name `xml:"xml"`
+	ReturnCode     string   `xml:"return_code"`      // 返回状态码
+	ReturnMsg      string   `xml:"return_msg"`       // 返回信息
+	ResultCode     string   `xml:"result_code"`      // 业务结果
+	ErrCode        string   `xml:"err_code"`         // 错误代码
+	ErrCodeDesc    string   `xml:"err_code_des"`     // 错误代码描述
+	PartnerTradeNo string   `xml:"partner_trade_no"` // 商户单号
+	AppID          string   `xml:"appid"`            // Appid
+	MchID          string   `xml:"mch_id"`           // 商户号
+	DetailID       string   `xml:"detail_id"`        // 付款单号
+	Status         string   `xml:"status"`           // 转账状态
+	Reason         string   `xml:"reason"`           // 失败原因
+	OpenID         string   `xml:"openid"`           // 收款用户openid
+	TransferName   string   `xml:"transfer_name"`    // 收款用户姓名
+	PaymentAmount  string   `xml:"payment_amount"`   // 付款金额
+	TransferTime   string   `xml:"transfer_time"`    // 发起转账的时间
+	PaymentTime    string   `xml:"payment_time"`     // 付款成功时间
+	Desc           string   `xml:"desc"`             // 企业付款备注
+}
+
+type payBankReq struct {
+	XMLName        xml.Name `xml:"xml"`
+	MchID          string   `xml:"mch_id"`           // 商户号
+	PartnerTradeNo string   `xml:"partner_trade_no"` // 商户企业付款单号
+	NonceStr       string   `xml:"nonce_str"`        // 随机字符串
+	EncBankNo      string   `xml:"enc_bank_no"`      // 收款方银行卡号
+	EncTrueName    string   `xml:"enc_true_name"`    // 收款方用户名
+	BankCode       string   `xml:"bank_code"`        // 收款方开户行
+	Amount         string   `xml:"amount"`           // 付款金额
+	Desc           string   `xml:"desc"`             // 付款说明
+}
+
+func (req payBankReq) URI() string {
+	return "https://api.mch.weixin.qq.com/mmpaysptrans/pay_bank"
+}
+
+// PayBankRsp is the response returned by /mmpaysptrans/pay_bank.
+type PayBankRsp struct {
+	XMLName        xml.Name `xml:"xml"`
+	ReturnCode     string   `xml:"return_code"`      // 返回状态码
+	ReturnMsg      string   `xml:"return_msg"`       // 返回信息
+	ResultCode     string   `xml:"result_code"`      // 业务结果
+	ErrCode        string   `xml:"err_code"`         // 错误代码
+	ErrCodeDesc    string   `xml:"err_code_des"`     // 错误代码描述
+	MchID          string   `xml:"mch_id"`           // 商户号
+	PartnerTradeNo string   `xml:"partner_trade_no"` // 商户企业付款单号
+	Amount         string   `xml:"amount"`           // 代付金额
+	NonceStr       string   `xml:"nonce_str"`        // 随机字符串
+	Sign           string   `xml:"sign"`             // 签名
+	PaymentNo      string   `xml:"payment_no"`       // 微信企业付款单号
+	CmmsAmt        string   `xml:"cmms_amt"`         // 手续费金额
+}
+
+type queryBankReq struct {
+	XMLName        xml.Name `xml:"xml"`
+	MchID          string   `xml:"mch_id"`           // 商户号
+	PartnerTradeNo string   `xml:"partner_trade_no"` // 商户企业付款单号
+	NonceStr       string   `xml:"nonce_str"`        // 随机字符串
+}
+
+func (req queryBankReq) URI() string {
+	return "https://api.mch.weixin.qq.com/mmpaysptrans/query_bank"
+}
+
+// QueryBankRsp is the response returned by /mmpaysptrans/query_bank.
+type QueryBankRsp struct {
+	XMLName        xml.Name `xml:"xml"`
+	ReturnCode     string   `xml:"return_code"`      // 返回状态码
+	ReturnMsg      string   `xml:"return_msg"`       // 返回信息
+	ResultCode     string   `xml:"result_code"`      // 业务结果
+	ErrCode        string   `xml:"err_code"`         // 错误代码
+	ErrCodeDesc    string   `xml:"err_code_des"`     // 错误代码描述
+	MchID          string   `xml:"mch_id"`           // 商户号
+	PartnerTradeNo string   `xml:"partner_trade_no"` // 商户企业付款单号
+	PaymentNo      string   `xml:"payment_no"`       // 付款单号
+	BankNoMD5      string   `xml:"bank_no_md5"`      // 银行卡号
+	TrueNameMD5    string   `xml:"true_name_md5"`    // 用户真实姓名
+	Amount         string   `xml:"amount"`           // 代付金额
+	Status         string   `xml:"status"`           // 代付单状态
+	CmmsAmt        string   `xml:"cmms_amt"`         // 手续费金额
+	CreateTime     string   `xml:"create_time"`      // 商户下单时间
+	PaySuccTime    string   `xml:"pay_succ_time"`    // 成功付款时间
+	Reason         string   `xml:"reason"`           // 失败原因
+}
+
+type getPublicKeyReq struct {
+	XMLName  xml.Name `xml:"xml"`
+	MchID    string   `xml:"mch_id"`    // 商户号
+	NonceStr string   `xml:"nonce_str"` // 随机字符串
+	SignType string   `xml:"sign_type"` // 签名类型
+}
+
+func (req getPublicKeyReq) URI() string {
+	return "https://fraud.mch.weixin.qq.com/risk/getpublickey"
+}
+
+// GetPublicKeyRsp is the response returned by /risk/getpublickey.
+type GetPublicKeyRsp struct {
+	XMLName     xml.Name `xml:"xml"`
+	ReturnCode  string   `xml:"return_code"`  // 返回状态码
+	ReturnMsg   string   `xml:"return_msg"`   // 返回信息
+	ResultCode  string   `xml:"result_code"`  // 业务结果
+	ErrCode     string   `xml:"err_code"`     // 错误代码
+	ErrCodeDesc string   `xml:"err_code_des"` // 错误代码描述
+	MchID       string   `xml:"mch_id"`       // 商户号
+	PubKey      string   `xml:"pub_key"`      // 密钥
+}
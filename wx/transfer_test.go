@@ -0,0 +1,99 @@
+package wx
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"net/url"
+	"testing"
+)
+
+func TestEncryptOAEP(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pubPEM := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PUBLIC KEY",
+		Bytes: x509.MarshalPKCS1PublicKey(&key.PublicKey),
+	})
+
+	pub, err := parseRSAPublicKey(pubPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encrypted, err := encryptOAEP(pub, "6225880137941234")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plain, err := rsa.DecryptOAEP(sha1.New(), rand.Reader, key, data, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(plain) != "6225880137941234" {
+		t.Errorf("returned: %s, expected: %s", plain, "6225880137941234")
+	}
+}
+
+func TestPayBank(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := newTestServer(map[string]string{
+		"return_code":      Success,
+		"result_code":      Success,
+		"mch_id":           "10000100",
+		"partner_trade_no": "1212121221227",
+		"amount":           "500",
+		"nonce_str":        "50780e0cca98c8c8e814883e5caa672e",
+		"payment_no":       "10000098201411111234567890",
+		"cmms_amt":         "100",
+	}, MD5)
+	defer srv.Close()
+
+	target, _ := url.Parse(srv.URL)
+	rewriter := &hostRewriter{target: target}
+	c := NewClient(Config{MchID: "10000100", AppKey: testAppKey})
+	c.tlsClient.Transport = rewriter
+	c.cache.pubKey = &key.PublicKey
+
+	opts := PayBankOptions{
+		PartnerTradeNo: "1212121221227",
+		BankNo:         "6225880137941234",
+		TrueName:       "张三",
+		BankCode:       "1001",
+		Amount:         500,
+	}
+	rsp, err := c.PayBank(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rsp.PaymentNo != "10000098201411111234567890" {
+		t.Errorf("returned: %s, expected: %s", rsp.PaymentNo, "10000098201411111234567890")
+	}
+	if rewriter.paths[0] != "/mmpaysptrans/pay_bank" {
+		t.Errorf("returned: %s, expected: /mmpaysptrans/pay_bank", rewriter.paths[0])
+	}
+
+	// the response is signed by another key
+	c = NewClient(Config{MchID: "10000100", AppKey: "0123456789abcdef0123456789abcdef"})
+	c.tlsClient.Transport = &hostRewriter{target: target}
+	c.cache.pubKey = &key.PublicKey
+	if _, err = c.PayBank(opts); err == nil {
+		t.Error("expected signature failure with response signed by another key")
+	}
+}
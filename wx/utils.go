@@ -1,8 +1,17 @@
 package wx
 
 import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
 	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
 	"fmt"
+	"io"
 	"reflect"
 	"sort"
 	"strconv"
@@ -25,6 +34,9 @@ func toMap(st interface{}) (map[string]string, error) {
 
 	for i := 0; i < val.NumField(); i++ {
 		sf := typ.Field(i)
+		if val.Field(i).Kind() != reflect.String {
+			continue
+		}
 		sv := val.Field(i).String()
 		if tag, ok := sf.Tag.Lookup("xml"); ok && tag != "" && tag != "xml" && sv != "" {
 			result[tag] = val.Field(i).String()
@@ -33,12 +45,49 @@ func toMap(st interface{}) (map[string]string, error) {
 	return result, nil
 }
 
+// xmlToMap decodes the children of the root element into a map.
+func xmlToMap(data []byte) (map[string]string, error) {
+	result := map[string]string{}
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	depth := 0
+	var key string
+	var value []byte
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			if depth == 2 {
+				key = t.Name.Local
+				value = value[:0]
+			}
+		case xml.CharData:
+			if depth == 2 {
+				value = append(value, t...)
+			}
+		case xml.EndElement:
+			if depth == 2 {
+				result[key] = string(value)
+			}
+			depth--
+		}
+	}
+	return result, nil
+}
+
 func generateNonceStr() string {
 	nonce := strconv.FormatInt(time.Now().UnixNano(), 36)
 	return fmt.Sprintf("%x", md5.Sum([]byte(nonce)))
 }
 
-func signature(params map[string]string, key string) string {
+func signature(params map[string]string, key, signType string) string {
 	excluded := map[string]string{}
 	for k, v := range params {
 		if k == "sign" {
@@ -63,19 +112,104 @@ func signature(params map[string]string, key string) string {
 	keyValueStr := strings.Join(keyValueConcat, "&")
 
 	keyValueSecret := keyValueStr + "&key=" + key
+	if signType == HMACSHA256 {
+		mac := hmac.New(sha256.New, []byte(key))
+		mac.Write([]byte(keyValueSecret))
+		return fmt.Sprintf("%X", mac.Sum(nil))
+	}
 	return fmt.Sprintf("%X", md5.Sum([]byte(keyValueSecret)))
 }
 
+// toXMLStr encodes params into XML in the order of keys, wrapping every value
+// in CDATA so that characters such as & and < are kept as is.
 func toXMLStr(params map[string]string) string {
-	xml := "<xml>"
-	for k, v := range params {
-		xml += fmt.Sprintf("<%s>%s</%s>", k, v, k)
+	var keys []string
+	for k := range params {
+		keys = append(keys, k)
 	}
-	xml += "</xml>"
-	return xml
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	buf.WriteString("<xml>")
+	for _, k := range keys {
+		// "]]>" ends CDATA, so split it into two sections
+		v := strings.Replace(params[k], "]]>", "]]]]><![CDATA[>", -1)
+		fmt.Fprintf(&buf, "<%s><![CDATA[%s]]></%s>", k, v, k)
+	}
+	buf.WriteString("</xml>")
+	return buf.String()
 }
 
 func generateTimestampStr() string {
 	const ChinaTimeZoneOffset = 8 * 60 * 60 // UTC + 8
 	return fmt.Sprintf("%d", time.Now().Unix()+ChinaTimeZoneOffset)
 }
+
+// decryptReqInfo decrypts req_info of refund notification, which is encrypted
+// by AES-256-ECB with the lowercase MD5 of key, padded by PKCS#7.
+func decryptReqInfo(reqInfo, key string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(reqInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher([]byte(fmt.Sprintf("%x", md5.Sum([]byte(key)))))
+	if err != nil {
+		return nil, err
+	}
+
+	size := block.BlockSize()
+	if len(data) == 0 || len(data)%size != 0 {
+		return nil, errors.New("invalid req_info length")
+	}
+
+	plain := make([]byte, len(data))
+	for i := 0; i < len(data); i += size {
+		block.Decrypt(plain[i:i+size], data[i:i+size])
+	}
+
+	return pkcs7Unpad(plain, size)
+}
+
+// pkcs7Unpad removes the PKCS#7 padding of data, rejecting malformed padding.
+func pkcs7Unpad(data []byte, size int) ([]byte, error) {
+	if len(data) == 0 || len(data)%size != 0 {
+		return nil, errors.New("invalid padded data length")
+	}
+
+	padding := int(data[len(data)-1])
+	if padding < 1 || padding > size {
+		return nil, errors.New("invalid padding")
+	}
+	for _, b := range data[len(data)-padding:] {
+		if int(b) != padding {
+			return nil, errors.New("invalid padding")
+		}
+	}
+	return data[:len(data)-padding], nil
+}
+
+// decryptAEADAES256GCM decrypts the resource of API v3, which is encrypted by
+// AEAD_AES_256_GCM with the APIv3 key.
+func decryptAEADAES256GCM(res EncryptedResource, key string) ([]byte, error) {
+	if res.Algorithm != AEADAES256GCM {
+		return nil, fmt.Errorf("unsupported algorithm %s", res.Algorithm)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(res.Ciphertext)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher([]byte(key))
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := cipher.NewGCMWithNonceSize(block, len(res.Nonce))
+	if err != nil {
+		return nil, err
+	}
+
+	return gcm.Open(nil, []byte(res.Nonce), data, []byte(res.AssociatedData))
+}
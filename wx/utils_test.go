@@ -0,0 +1,151 @@
+package wx
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/md5"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testAppKey = "192006250b4c09247ec02edce69f6a2d"
+
+func TestSignature(t *testing.T) {
+	params := map[string]string{
+		"appid":       "wxd930ea5d5a258f4f",
+		"mch_id":      "10000100",
+		"device_info": "1000",
+		"body":        "test",
+		"nonce_str":   "ibuaiVcKdpRxkhJA",
+		"sign":        "ignored",
+		"attach":      "",
+	}
+
+	tests := []struct {
+		signType string
+		expected string
+	}{
+		{"", "9A0A8659F005D6984697E2CA0A9CF3B7"},
+		{MD5, "9A0A8659F005D6984697E2CA0A9CF3B7"},
+		{HMACSHA256, "6A9AE1657590FD6257D693A078E1C3E4BB6BA4DC30B23E0EE2496E54170DACD6"},
+	}
+
+	for _, tt := range tests {
+		sign := signature(params, testAppKey, tt.signType)
+		if sign != tt.expected {
+			t.Errorf("sign type %q returned: %s, expected: %s", tt.signType, sign, tt.expected)
+		}
+	}
+}
+
+func TestRefundNotify(t *testing.T) {
+	plain := "<root><out_refund_no><![CDATA[131811191610442717309]]></out_refund_no>" +
+		"<out_trade_no><![CDATA[71106718111915575302817]]></out_trade_no>" +
+		"<refund_account><![CDATA[REFUND_SOURCE_RECHARGE_FUNDS]]></refund_account>" +
+		"<refund_fee><![CDATA[3960]]></refund_fee>" +
+		"<refund_id><![CDATA[50000408942018111907145868882]]></refund_id>" +
+		"<refund_recv_accout><![CDATA[支付用户零钱]]></refund_recv_accout>" +
+		"<refund_status><![CDATA[SUCCESS]]></refund_status>" +
+		"<success_time><![CDATA[2018-11-19 16:24:13]]></success_time>" +
+		"<total_fee><![CDATA[3960]]></total_fee>" +
+		"<transaction_id><![CDATA[4200000215201811190261405420]]></transaction_id></root>"
+
+	body := "<xml><return_code>SUCCESS</return_code><appid>wx2421b1c4370ec43b</appid>" +
+		"<mch_id>10000100</mch_id><nonce_str>TeqClE3i0mvn3DrK</nonce_str>" +
+		"<req_info>" + encryptReqInfo(t, plain, testAppKey) + "</req_info></xml>"
+
+	c := NewClient(Config{AppKey: testAppKey})
+	req := httptest.NewRequest(http.MethodPost, "/notify/refund", strings.NewReader(body))
+	result, err := c.RefundNotify(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.RefundID != "50000408942018111907145868882" {
+		t.Errorf("returned: %s, expected: %s", result.RefundID, "50000408942018111907145868882")
+	}
+	if result.RefundStatus != RefundSuccess {
+		t.Errorf("returned: %s, expected: %s", result.RefundStatus, RefundSuccess)
+	}
+	if result.RefundRecvAccout != "支付用户零钱" {
+		t.Errorf("returned: %s, expected: %s", result.RefundRecvAccout, "支付用户零钱")
+	}
+	if result.MchID != "10000100" {
+		t.Errorf("returned: %s, expected: %s", result.MchID, "10000100")
+	}
+}
+
+func TestPKCS7Unpad(t *testing.T) {
+	valid := append([]byte("0123456789abc"), 3, 3, 3)
+	data, err := pkcs7Unpad(valid, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "0123456789abc" {
+		t.Errorf("returned: %q, expected: %q", data, "0123456789abc")
+	}
+
+	full := bytes.Repeat([]byte{16}, 16)
+	if data, err = pkcs7Unpad(full, 16); err != nil || len(data) != 0 {
+		t.Errorf("returned: %q, %v, expected a full block of padding removed", data, err)
+	}
+
+	invalid := [][]byte{
+		nil,
+		[]byte("0123456789abcde"),
+		append([]byte("0123456789abcde"), 0),
+		append([]byte("0123456789abcde"), 17),
+		append([]byte("0123456789abc"), 2, 9, 3),
+		append([]byte("0123456789abc"), 3, 3, 4),
+	}
+	for i, d := range invalid {
+		if _, err = pkcs7Unpad(d, 16); err == nil {
+			t.Errorf("case %d: expected padding error", i)
+		}
+	}
+}
+
+func encryptReqInfo(t *testing.T, plain, key string) string {
+	block, err := aes.NewCipher([]byte(fmt.Sprintf("%x", md5.Sum([]byte(key)))))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	size := block.BlockSize()
+	padding := size - len(plain)%size
+	data := append([]byte(plain), bytes.Repeat([]byte{byte(padding)}, padding)...)
+	for i := 0; i < len(data); i += size {
+		block.Encrypt(data[i:i+size], data[i:i+size])
+	}
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+func TestToXMLStr(t *testing.T) {
+	params := map[string]string{
+		"return_code": Success,
+		"body":        `Tom & Jerry <"DVD"> ]]> 'box'`,
+		"attach":      "",
+	}
+
+	xmlStr := toXMLStr(params)
+	expected := `<xml><attach><![CDATA[]]></attach>` +
+		`<body><![CDATA[Tom & Jerry <"DVD"> ]]]]><![CDATA[> 'box']]></body>` +
+		`<return_code><![CDATA[SUCCESS]]></return_code></xml>`
+	if xmlStr != expected {
+		t.Errorf("returned: %s, expected: %s", xmlStr, expected)
+	}
+
+	values, err := xmlToMap([]byte(xmlStr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range params {
+		if values[k] != v {
+			t.Errorf("returned: %q, expected: %q", values[k], v)
+		}
+	}
+}
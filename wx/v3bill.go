@@ -0,0 +1,152 @@
+package wx
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha1"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// constants for type of trade bill.
+const (
+	BillTypeAll     = "ALL"     // 当日所有订单信息（不含充值退款订单）
+	BillTypeSuccess = "SUCCESS" // 当日成功支付的订单（不含充值退款订单）
+	BillTypeRefund  = "REFUND"  // 当日退款订单（不含充值退款订单）
+)
+
+// BillInfo is the address to download a bill and its hash.
+type BillInfo struct {
+	HashType    string `json:"hash_type"`    // 哈希类型
+	HashValue   string `json:"hash_value"`   // 哈希值
+	DownloadURL string `json:"download_url"` // 账单下载地址，30s内有效
+}
+
+// TradeBillInfo applies for the trade bill of billType on billDate, which is
+// formatted as 2006-01-02.
+func (c *V3Client) TradeBillInfo(billDate, billType string) (*BillInfo, error) {
+	query := url.Values{}
+	query.Set("bill_date", billDate)
+	query.Set("bill_type", billType)
+	query.Set("tar_type", "GZIP")
+	return c.billInfo("/v3/bill/tradebill?" + query.Encode())
+}
+
+// FundFlowBillInfo applies for the fund flow bill of accountType on billDate,
+// which is formatted as 2006-01-02. accountType is one of FundsAccountBasic,
+// FundsAccountOperation and FundsAccountFees.
+func (c *V3Client) FundFlowBillInfo(billDate, accountType string) (*BillInfo, error) {
+	query := url.Values{}
+	query.Set("bill_date", billDate)
+	query.Set("account_type", accountType)
+	query.Set("tar_type", "GZIP")
+	return c.billInfo("/v3/bill/fundflowbill?" + query.Encode())
+}
+
+func (c *V3Client) billInfo(uri string) (*BillInfo, error) {
+	rsp := &BillInfo{}
+	if err := c.doRequest(http.MethodGet, uri, nil, rsp); err != nil {
+		return nil, err
+	}
+	return rsp, nil
+}
+
+// DownloadBill streams the bill of info into w, decompressed if gzipped. The
+// hash is verified once the bill is written, so w must be discarded on error.
+// Receipts of transfers are downloaded in the same way.
+func (c *V3Client) DownloadBill(info *BillInfo, w io.Writer) error {
+	var h hash.Hash
+	switch info.HashType {
+	case "SHA1":
+		h = sha1.New()
+	case "SHA256":
+		h = sha256.New()
+	default:
+		return fmt.Errorf("unsupported hash type %s", info.HashType)
+	}
+
+	u, err := url.Parse(info.DownloadURL)
+	if err != nil {
+		return err
+	}
+
+	auth, err := c.authorization(http.MethodGet, u.RequestURI(), nil)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, info.DownloadURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", auth)
+
+	rsp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode < 200 || rsp.StatusCode > 299 {
+		data, _ := ioutil.ReadAll(rsp.Body)
+		return newV3Error(rsp, data)
+	}
+
+	// the bill is gzipped as requested by tar_type, while its hash is that of
+	// the bill decompressed
+	var r io.Reader = bufio.NewReader(rsp.Body)
+	if magic, _ := r.(*bufio.Reader).Peek(2); bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	if _, err = io.Copy(io.MultiWriter(w, h), r); err != nil {
+		return err
+	}
+
+	if sum := fmt.Sprintf("%x", h.Sum(nil)); !strings.EqualFold(sum, info.HashValue) {
+		return fmt.Errorf("hash failed, expected %s, got %s", info.HashValue, sum)
+	}
+	return nil
+}
+
+// DownloadTradeBill downloads the trade bill of billType on billDate, which is
+// formatted as 2006-01-02.
+func (c *V3Client) DownloadTradeBill(billDate, billType string) (*TradeBill, error) {
+	info, err := c.TradeBillInfo(billDate, billType)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err = c.DownloadBill(info, &buf); err != nil {
+		return nil, err
+	}
+	return parseTradeBill(buf.Bytes())
+}
+
+// DownloadFundFlowBill downloads the fund flow bill of accountType on
+// billDate, which is formatted as 2006-01-02.
+func (c *V3Client) DownloadFundFlowBill(billDate, accountType string) (*FundFlowBill, error) {
+	info, err := c.FundFlowBillInfo(billDate, accountType)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err = c.DownloadBill(info, &buf); err != nil {
+		return nil, err
+	}
+	return parseFundFlowBill(buf.Bytes())
+}
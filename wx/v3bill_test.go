@@ -0,0 +1,144 @@
+package wx
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha1"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDownloadTradeBill(t *testing.T) {
+	data := "交易时间,公众账号ID,商户号,特约商户号,设备号,微信订单号,商户订单号,用户标识,交易类型,交易状态,付款银行,货币种类,应结订单金额,代金券金额,微信退款单号,商户退款单号,退款金额,充值券退款金额,退款类型,退款状态,商品名称,商户数据包,手续费,费率,订单金额,申请退款金额,费率备注\r\n" +
+		"`2019-06-11 10:08:22,`wxd678efh567hg6787,`1230000109,`0,`,`4200000340201906113184537290,`1217752501201407033233368018,`oUpF8uMuAJO_M2pxb1Q9zNjWeS6o,`JSAPI,`SUCCESS,`OTHERS,`CNY,`0.01,`0.00,`0,`0,`0.00,`0.00,`,`,`QQ公仔,`,`0.00000,`0.60%,`0.01,`0.00,`\r\n" +
+		"总交易单数,应结订单总金额,退款总金额,充值券退款总金额,手续费总金额,订单总金额,申请退款总金额\r\n" +
+		"`1,`0.01,`0.00,`0.00,`0.00000,`0.01,`0.00\r\n"
+
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte(data))
+	zw.Close()
+
+	env := newV3TestEnv(t)
+	hash := fmt.Sprintf("%X", sha1.Sum([]byte(data)))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := env.checkAuthorization(r, nil); err != nil {
+			t.Error(err)
+		}
+
+		switch r.URL.Path {
+		case "/v3/bill/tradebill":
+			if r.URL.Query().Get("bill_date") != "2019-06-11" || r.URL.Query().Get("bill_type") != BillTypeAll {
+				t.Errorf("returned: %s", r.URL.RawQuery)
+			}
+			rsp := fmt.Sprintf(`{"hash_type":"SHA1","hash_value":"%s","download_url":"https://api.mch.weixin.qq.com/v3/billdownload/file?token=6XIv5TUPto7pByrTQKhd6kwvyKLG2uY2wMMR8cNXqaA_Cv_isgaUtBzp4QtiozLO"}`, hash)
+			env.sign(t, w.Header(), rsp)
+			fmt.Fprint(w, rsp)
+		case "/v3/billdownload/file":
+			w.Write(gz.Bytes())
+		}
+	}))
+	defer srv.Close()
+
+	c := env.client(srv)
+	bill, err := c.DownloadTradeBill("2019-06-11", BillTypeAll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bill.Records) != 1 || bill.Records[0].TransactionID != "4200000340201906113184537290" || bill.Records[0].Rate != "0.60%" {
+		t.Errorf("returned: %#v", bill.Records)
+	}
+	if bill.Summary.TotalCount != "1" || bill.Summary.TotalFee != "0.01" {
+		t.Errorf("returned: %#v", bill.Summary)
+	}
+
+	info := &BillInfo{
+		HashType:    "SHA1",
+		HashValue:   "0000000000000000000000000000000000000000",
+		DownloadURL: "https://api.mch.weixin.qq.com/v3/billdownload/file?token=abc",
+	}
+	if err = c.DownloadBill(info, &bytes.Buffer{}); err == nil {
+		t.Error("expected hash failure")
+	}
+}
+
+// tradeBillServer serves data as the trade bill of billType, uncompressed.
+func (env *v3TestEnv) tradeBillServer(t *testing.T, billType, data string) *httptest.Server {
+	hash := fmt.Sprintf("%X", sha1.Sum([]byte(data)))
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v3/bill/tradebill":
+			if r.URL.Query().Get("bill_type") != billType {
+				t.Errorf("returned: %s, expected bill_type %s", r.URL.RawQuery, billType)
+			}
+			rsp := fmt.Sprintf(`{"hash_type":"SHA1","hash_value":"%s","download_url":"https://api.mch.weixin.qq.com/v3/billdownload/file?token=abc"}`, hash)
+			env.sign(t, w.Header(), rsp)
+			fmt.Fprint(w, rsp)
+		case "/v3/billdownload/file":
+			fmt.Fprint(w, data)
+		}
+	}))
+}
+
+func TestDownloadTradeBillSuccess(t *testing.T) {
+	data := "交易时间,公众账号ID,商户号,特约商户号,设备号,微信订单号,商户订单号,用户标识,交易类型,交易状态,付款银行,货币种类,应结订单金额,代金券金额,商品名称,商户数据包,手续费,费率,订单金额,费率备注\r\n" +
+		"`2019-06-11 10:08:22,`wxd678efh567hg6787,`1230000109,`0,`,`4200000340201906113184537290,`1217752501201407033233368018,`oUpF8uMuAJO_M2pxb1Q9zNjWeS6o,`JSAPI,`SUCCESS,`OTHERS,`CNY,`0.01,`0.00,`QQ公仔,`attach,`0.00000,`0.60%,`0.01,`\r\n" +
+		"总交易单数,应结订单总金额,退款总金额,充值券退款总金额,手续费总金额,订单总金额,申请退款总金额\r\n" +
+		"`1,`0.01,`0.00,`0.00,`0.00000,`0.01,`0.00\r\n"
+
+	env := newV3TestEnv(t)
+	srv := env.tradeBillServer(t, BillTypeSuccess, data)
+	defer srv.Close()
+
+	bill, err := env.client(srv).DownloadTradeBill("2019-06-11", BillTypeSuccess)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bill.Records) != 1 {
+		t.Fatalf("returned: %d records, expected: 1", len(bill.Records))
+	}
+
+	record := bill.Records[0]
+	if record.Body != "QQ公仔" || record.Attach != "attach" || record.Rate != "0.60%" || record.TotalFee != "0.01" {
+		t.Errorf("returned: %#v", record)
+	}
+	if record.RefundID != "" || record.RefundFee != "" || record.ApplyRefundFee != "" {
+		t.Errorf("returned: %#v, expected no refund", record)
+	}
+	if bill.Summary.TotalCount != "1" || bill.Summary.TotalFee != "0.01" {
+		t.Errorf("returned: %#v", bill.Summary)
+	}
+}
+
+func TestDownloadTradeBillRefund(t *testing.T) {
+	data := "交易时间,公众账号ID,商户号,特约商户号,设备号,微信订单号,商户订单号,用户标识,交易类型,交易状态,付款银行,货币种类,应结订单金额,代金券金额,退款申请时间,退款成功时间,微信退款单号,商户退款单号,退款金额,充值券退款金额,退款类型,退款状态,商品名称,商户数据包,手续费,费率,订单金额,申请退款金额,费率备注\r\n" +
+		"`2019-06-11 10:08:22,`wxd678efh567hg6787,`1230000109,`0,`,`4200000340201906113184537290,`1217752501201407033233368018,`oUpF8uMuAJO_M2pxb1Q9zNjWeS6o,`JSAPI,`REFUND,`OTHERS,`CNY,`0.00,`0.00,`2019-06-12 09:00:00,`2019-06-12 09:00:05,`50000300702019061209992372938,`1217752501201407033233368019,`0.01,`0.00,`ORIGINAL,`SUCCESS,`QQ公仔,`,`0.00000,`0.60%,`0.01,`0.01,`\r\n" +
+		"总交易单数,应结订单总金额,退款总金额,充值券退款总金额,手续费总金额,订单总金额,申请退款总金额\r\n" +
+		"`1,`0.00,`0.01,`0.00,`0.00000,`0.01,`0.01\r\n"
+
+	env := newV3TestEnv(t)
+	srv := env.tradeBillServer(t, BillTypeRefund, data)
+	defer srv.Close()
+
+	bill, err := env.client(srv).DownloadTradeBill("2019-06-11", BillTypeRefund)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bill.Records) != 1 {
+		t.Fatalf("returned: %d records, expected: 1", len(bill.Records))
+	}
+
+	record := bill.Records[0]
+	if record.RefundApplyTime != "2019-06-12 09:00:00" || record.RefundSuccessTime != "2019-06-12 09:00:05" {
+		t.Errorf("returned: %#v", record)
+	}
+	if record.RefundID != "50000300702019061209992372938" || record.RefundStatus != RefundSuccess ||
+		record.Body != "QQ公仔" || record.ApplyRefundFee != "0.01" {
+		t.Errorf("returned: %#v", record)
+	}
+	if bill.Summary.RefundFee != "0.01" {
+		t.Errorf("returned: %#v", bill.Summary)
+	}
+}
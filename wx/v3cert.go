@@ -0,0 +1,225 @@
+package wx
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// minRefreshInterval is the minimum interval between downloads of platform
+// certificates on demand, so that forged serial numbers in notifications
+// cannot make every request download them.
+const minRefreshInterval = time.Minute
+
+// CertificateManager downloads platform certificates of Weixin, decrypts them
+// with the APIv3 key and caches them by serial number. It implements Verifier.
+type CertificateManager struct {
+	// OnError, if not nil, is called with the errors of refreshing in
+	// background, it must be set before Start.
+	OnError func(error)
+
+	client *V3Client
+
+	mu          sync.RWMutex
+	certs       map[string]*x509.Certificate
+	unknown     map[string]bool // serial numbers not found after a refresh
+	lastRefresh time.Time
+	stop        chan struct{}
+
+	refreshMu sync.Mutex
+}
+
+// NewCertificateManager returns a *CertificateManager downloading with
+// client, and sets it as the Verifier of client.
+func NewCertificateManager(client *V3Client) *CertificateManager {
+	m := &CertificateManager{
+		client:  client,
+		certs:   map[string]*x509.Certificate{},
+		unknown: map[string]bool{},
+	}
+	client.WithVerifier(m)
+	return m
+}
+
+// Refresh downloads the platform certificates and replaces those cached.
+func (m *CertificateManager) Refresh() error {
+	return m.refresh(false)
+}
+
+// refresh downloads the platform certificates, on demand at most once every
+// minRefreshInterval.
+func (m *CertificateManager) refresh(onDemand bool) error {
+	m.refreshMu.Lock()
+	defer m.refreshMu.Unlock()
+
+	m.mu.Lock()
+	if onDemand && time.Since(m.lastRefresh) < minRefreshInterval {
+		m.mu.Unlock()
+		return errors.New("platform certificates refreshed too recently")
+	}
+	m.lastRefresh = time.Now()
+	m.mu.Unlock()
+
+	header, data, err := m.client.send(http.MethodGet, "/v3/certificates", nil, nil)
+	if err != nil {
+		return err
+	}
+
+	rsp := certificatesRsp{}
+	if err = json.Unmarshal(data, &rsp); err != nil {
+		return err
+	}
+
+	certs := map[string]*x509.Certificate{}
+	now := time.Now()
+	for _, info := range rsp.Data {
+		plain, err := decryptAEADAES256GCM(info.EncryptCertificate, m.client.config.APIv3Key)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt certificate %s, %v", info.SerialNo, err)
+		}
+
+		block, _ := pem.Decode(plain)
+		if block == nil {
+			return fmt.Errorf("failed to decode certificate %s", info.SerialNo)
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return err
+		}
+
+		if serialNumber(cert) != info.SerialNo {
+			return fmt.Errorf("serial number mismatch, expected %s, got %s", info.SerialNo, serialNumber(cert))
+		}
+		if now.Before(cert.NotBefore) || now.After(cert.NotAfter) {
+			continue
+		}
+		certs[info.SerialNo] = cert
+	}
+
+	if len(certs) == 0 {
+		return errors.New("no valid platform certificate")
+	}
+
+	// the response is signed by one of the certificates it contains, merged
+	// with those already trusted during rotation
+	verifier := certificateVerifier{}
+	m.mu.RLock()
+	for serialNo, cert := range m.certs {
+		verifier[serialNo] = cert
+	}
+	m.mu.RUnlock()
+	for serialNo, cert := range certs {
+		verifier[serialNo] = cert
+	}
+
+	message := fmt.Sprintf("%s\n%s\n%s\n", header.Get("Wechatpay-Timestamp"), header.Get("Wechatpay-Nonce"), data)
+	if err = verifier.Verify(header.Get("Wechatpay-Serial"), []byte(message), header.Get("Wechatpay-Signature")); err != nil {
+		return fmt.Errorf("signature failed, %v", err)
+	}
+
+	m.mu.Lock()
+	m.certs = certs
+	m.unknown = map[string]bool{}
+	m.mu.Unlock()
+	return nil
+}
+
+// Start downloads the platform certificates and refreshes them every interval
+// in background, 12 hours is recommended. It returns the error of the first
+// download, later errors are passed to OnError and retried at the next
+// interval.
+func (m *CertificateManager) Start(interval time.Duration) error {
+	if err := m.Refresh(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.stop != nil {
+		return nil
+	}
+	stop := make(chan struct{})
+	m.stop = stop
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := m.Refresh(); err != nil && m.OnError != nil {
+					m.OnError(err)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop stops refreshing in background.
+func (m *CertificateManager) Stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.stop != nil {
+		close(m.stop)
+		m.stop = nil
+	}
+}
+
+// Certificate returns the platform certificate of serialNo.
+func (m *CertificateManager) Certificate(serialNo string) (*x509.Certificate, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	cert, ok := m.certs[serialNo]
+	return cert, ok
+}
+
+// Verify verifies signature with the platform certificate of serialNo, which
+// is downloaded if unknown, as Weixin may have rotated its certificates.
+// Serial numbers still unknown after downloading are rejected until the next
+// refresh.
+func (m *CertificateManager) Verify(serialNo string, message []byte, signature string) error {
+	cert, ok := m.Certificate(serialNo)
+	if !ok {
+		m.mu.RLock()
+		unknown := m.unknown[serialNo]
+		m.mu.RUnlock()
+		if unknown {
+			return fmt.Errorf("platform certificate %s not found", serialNo)
+		}
+
+		if err := m.refresh(true); err != nil {
+			// a concurrent refresh may have downloaded it just before
+			if cert, ok = m.Certificate(serialNo); !ok {
+				return err
+			}
+		} else if cert, ok = m.Certificate(serialNo); !ok {
+			m.mu.Lock()
+			m.unknown[serialNo] = true
+			m.mu.Unlock()
+			return fmt.Errorf("platform certificate %s not found", serialNo)
+		}
+	}
+	return verifySHA256WithRSA(cert, message, signature)
+}
+
+type certificatesRsp struct {
+	Data []certificateInfo `json:"data"`
+}
+
+type certificateInfo struct {
+	SerialNo           string            `json:"serial_no"`           // 证书序列号
+	EffectiveTime      string            `json:"effective_time"`      // 证书启用时间
+	ExpireTime         string            `json:"expire_time"`         // 证书过期时间
+	EncryptCertificate EncryptedResource `json:"encrypt_certificate"` // 证书信息
+}
@@ -0,0 +1,205 @@
+package wx
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// encryptResource encrypts plain as Weixin does with the APIv3 key.
+func encryptResource(t *testing.T, plain, key, associatedData string) EncryptedResource {
+	block, err := aes.NewCipher([]byte(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nonce := "fdasflkja484"
+	data := gcm.Seal(nil, []byte(nonce), []byte(plain), []byte(associatedData))
+	return EncryptedResource{
+		Algorithm:      AEADAES256GCM,
+		Ciphertext:     base64.StdEncoding.EncodeToString(data),
+		AssociatedData: associatedData,
+		Nonce:          nonce,
+	}
+}
+
+// certificatesHandler serves the platform certificate of env encrypted with
+// key.
+func (env *v3TestEnv) certificatesHandler(t *testing.T, key string, requests *int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+		certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: env.platformCrt.Raw})
+		rsp := certificatesRsp{Data: []certificateInfo{{
+			SerialNo:           serialNumber(env.platformCrt),
+			EffectiveTime:      "2018-06-08T10:34:56+08:00",
+			ExpireTime:         "2023-06-08T10:34:56+08:00",
+			EncryptCertificate: encryptResource(t, string(certPEM), key, "certificate"),
+		}}}
+		data, _ := json.Marshal(rsp)
+		env.sign(t, w.Header(), string(data))
+		fmt.Fprint(w, string(data))
+	}
+}
+
+func TestCertificateManager(t *testing.T) {
+	env := newV3TestEnv(t)
+	requests := 0
+	srv := httptest.NewServer(env.certificatesHandler(t, "0123456789abcdef0123456789abcdef", &requests))
+	defer srv.Close()
+
+	c := env.client(srv)
+	m := NewCertificateManager(c)
+
+	serialNo := serialNumber(env.platformCrt)
+	message := []byte("1554208460\n593BEC0C930BF1AFEB40B4A08C8FB242\n{}\n")
+	sig, err := signSHA256WithRSA(env.platformKey, message)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the unknown certificate is downloaded on first use
+	if err = m.Verify(serialNo, message, sig); err != nil {
+		t.Fatal(err)
+	}
+	if err = m.Verify(serialNo, message, sig); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 1 {
+		t.Errorf("returned: %d requests, expected: 1", requests)
+	}
+
+	if err = m.Verify(serialNo, []byte("tampered"), sig); err == nil {
+		t.Error("expected signature failure with tampered message")
+	}
+	if err = m.Verify("UNKNOWN", message, sig); err == nil {
+		t.Error("expected failure with unknown serial number")
+	}
+	if requests != 1 {
+		t.Errorf("returned: %d requests, expected: 1 within the minimum refresh interval", requests)
+	}
+
+	// unknown serial numbers are rejected without downloading again
+	m.mu.Lock()
+	m.lastRefresh = time.Now().Add(-minRefreshInterval)
+	m.mu.Unlock()
+	for i := 0; i < 3; i++ {
+		if err = m.Verify("FORGED", message, sig); err == nil {
+			t.Error("expected failure with forged serial number")
+		}
+	}
+	if requests != 2 {
+		t.Errorf("returned: %d requests, expected: 2", requests)
+	}
+}
+
+func TestCertificateManagerConcurrentVerify(t *testing.T) {
+	env := newV3TestEnv(t)
+	requests := 0
+	handler := env.certificatesHandler(t, "0123456789abcdef0123456789abcdef", &requests)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// keep the download in flight while the other goroutines miss the cache
+		time.Sleep(50 * time.Millisecond)
+		handler(w, r)
+	}))
+	defer srv.Close()
+
+	m := NewCertificateManager(env.client(srv))
+
+	serialNo := serialNumber(env.platformCrt)
+	message := []byte("1554208460\n593BEC0C930BF1AFEB40B4A08C8FB242\n{}\n")
+	sig, err := signSHA256WithRSA(env.platformKey, message)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- m.Verify(serialNo, message, sig)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("returned: %d requests, expected: 1", requests)
+	}
+}
+
+func TestCertificateManagerOnError(t *testing.T) {
+	env := newV3TestEnv(t)
+	requests := 0
+	handler := env.certificatesHandler(t, "0123456789abcdef0123456789abcdef", &requests)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests > 0 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		handler(w, r)
+	}))
+	defer srv.Close()
+
+	errs := make(chan error, 1)
+	m := NewCertificateManager(env.client(srv))
+	m.OnError = func(err error) {
+		select {
+		case errs <- err:
+		default:
+		}
+	}
+	if err := m.Start(10 * time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	defer m.Stop()
+
+	select {
+	case <-errs:
+	case <-time.After(time.Second):
+		t.Error("expected the error of refreshing in background")
+	}
+}
+
+func TestCertificateManagerStart(t *testing.T) {
+	env := newV3TestEnv(t)
+	requests := 0
+	srv := httptest.NewServer(env.certificatesHandler(t, "fedcba9876543210fedcba9876543210", &requests))
+	defer srv.Close()
+
+	m := NewCertificateManager(env.client(srv))
+	if err := m.Start(time.Hour); err == nil {
+		m.Stop()
+		t.Error("expected the error of the first download")
+	}
+}
+
+func TestCertificateManagerWrongKey(t *testing.T) {
+	env := newV3TestEnv(t)
+	requests := 0
+	srv := httptest.NewServer(env.certificatesHandler(t, "fedcba9876543210fedcba9876543210", &requests))
+	defer srv.Close()
+
+	m := NewCertificateManager(env.client(srv))
+	if err := m.Refresh(); err == nil {
+		t.Error("expected decryption failure with wrong APIv3 key")
+	}
+}
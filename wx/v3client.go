@@ -0,0 +1,317 @@
+package wx
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// v3Host is the domain of API v3.
+const v3Host = "https://api.mch.weixin.qq.com"
+
+// defaultV3Timeout is the timeout of requests to API v3 unless an http.Client
+// is set with WithHTTPClient.
+const defaultV3Timeout = 30 * time.Second
+
+// authSchema is the schema of Authorization header in API v3.
+const authSchema = "WECHATPAY2-SHA256-RSA2048"
+
+// AEADAES256GCM is the algorithm of resources encrypted with the APIv3 key.
+const AEADAES256GCM = "AEAD_AES_256_GCM"
+
+// constants for error code of API v3.
+const (
+	CodeSystemError       = "SYSTEM_ERROR"        // 系统错误
+	CodeParamError        = "PARAM_ERROR"         // 参数错误
+	CodeInvalidRequest    = "INVALID_REQUEST"     // 请求参数符合参数格式，但不符合业务规则
+	CodeSignError         = "SIGN_ERROR"          // 签名错误
+	CodeNoAuth            = "NO_AUTH"             // 商户无权限
+	CodeNotEnough         = "NOT_ENOUGH"          // 余额不足
+	CodeFrequencyLimited  = "FREQUENCY_LIMITED"   // 频率超限
+	CodeResourceNotExists = "RESOURCE_NOT_EXISTS" // 资源不存在
+	CodeOrderNotExist     = "ORDER_NOT_EXIST"     // 订单不存在
+	CodeOrderClosed       = "ORDERCLOSED"         // 订单已关闭
+	CodeOrderPaid         = "ORDERPAID"           // 订单已支付
+	CodeAccountError      = "ACCOUNTERROR"        // 账号异常
+	CodeBankError         = "BANKERROR"           // 银行系统异常
+)
+
+// V3Config contains configuration of API v3.
+type V3Config struct {
+	AppID      string
+	MchID      string
+	SerialNo   string          // 商户API证书序列号
+	PrivateKey *rsa.PrivateKey // 商户API私钥
+	APIv3Key   string          // APIv3密钥
+	NotifyURL  string
+
+	// ServiceID is the service of Pay Score, required by its APIs only.
+	ServiceID string
+}
+
+// V3Client handles transactions of API v3, in JSON signed by the merchant
+// private key.
+type V3Client struct {
+	config   V3Config
+	client   http.Client
+	verifier Verifier
+}
+
+// Verifier verifies signatures made by Weixin with the platform certificate
+// of serialNo.
+type Verifier interface {
+	Verify(serialNo string, message []byte, signature string) error
+}
+
+// V3Error is the error returned by API v3, see the Code constants.
+type V3Error struct {
+	StatusCode int             `json:"-"`
+	RequestID  string          `json:"-"`
+	Code       string          `json:"code"`
+	Message    string          `json:"message"`
+	Detail     json.RawMessage `json:"detail,omitempty"`
+}
+
+func (e *V3Error) Error() string {
+	return fmt.Sprintf("status %d, code %s, message %s, request id %s", e.StatusCode, e.Code, e.Message, e.RequestID)
+}
+
+// EncryptedResource is data encrypted by AEAD_AES_256_GCM with the APIv3 key.
+type EncryptedResource struct {
+	Algorithm      string `json:"algorithm"`               // 加密算法类型
+	Ciphertext     string `json:"ciphertext"`              // 数据密文
+	AssociatedData string `json:"associated_data"`         // 附加数据
+	Nonce          string `json:"nonce"`                   // 随机串
+	OriginalType   string `json:"original_type,omitempty"` // 原始类型
+}
+
+// NewV3Client returns a *V3Client ready to use once a Verifier is set with
+// WithVerifier.
+func NewV3Client(cfg V3Config) *V3Client {
+	return &V3Client{
+		config: cfg,
+		client: http.Client{Timeout: defaultV3Timeout},
+	}
+}
+
+// WithVerifier configures a *V3Client with the Verifier of responses.
+func (c *V3Client) WithVerifier(verifier Verifier) {
+	c.verifier = verifier
+}
+
+// WithHTTPClient configures a *V3Client to send requests with client, whose
+// timeout should allow downloading bills.
+func (c *V3Client) WithHTTPClient(client *http.Client) {
+	c.client = *client
+}
+
+// ParsePrivateKey parses the merchant private key in apiclient_key.pem, in
+// PKCS#8 or PKCS#1.
+func ParsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("failed to decode private key")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("need a RSA private key, got %T", key)
+	}
+	return rsaKey, nil
+}
+
+// certificateVerifier verifies with fixed platform certificates.
+type certificateVerifier map[string]*x509.Certificate
+
+// NewCertificateVerifier returns a Verifier with the platform certificates
+// given, which must be replaced before they expire.
+func NewCertificateVerifier(certs ...*x509.Certificate) Verifier {
+	v := certificateVerifier{}
+	for _, cert := range certs {
+		v[serialNumber(cert)] = cert
+	}
+	return v
+}
+
+func (v certificateVerifier) Verify(serialNo string, message []byte, signature string) error {
+	cert, ok := v[serialNo]
+	if !ok {
+		return fmt.Errorf("platform certificate %s not found", serialNo)
+	}
+	return verifySHA256WithRSA(cert, message, signature)
+}
+
+// serialNumber returns the serial number of cert in uppercase hex.
+func serialNumber(cert *x509.Certificate) string {
+	return fmt.Sprintf("%X", cert.SerialNumber)
+}
+
+// signSHA256WithRSA signs message with key and encodes it in base64.
+func signSHA256WithRSA(key *rsa.PrivateKey, message []byte) (string, error) {
+	hashed := sha256.Sum256(message)
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hashed[:])
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(sig), nil
+}
+
+// verifySHA256WithRSA verifies the signature in base64 of message with cert.
+func verifySHA256WithRSA(cert *x509.Certificate, message []byte, signature string) error {
+	pub, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("need a RSA public key, got %T", cert.PublicKey)
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return err
+	}
+
+	hashed := sha256.Sum256(message)
+	return rsa.VerifyPKCS1v15(pub, crypto.SHA256, hashed[:], sig)
+}
+
+// authorization returns the Authorization header of the request, uri is the
+// path with query.
+func (c *V3Client) authorization(method, uri string, body []byte) (string, error) {
+	nonce := generateNonceStr()
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	message := fmt.Sprintf("%s\n%s\n%s\n%s\n%s\n", method, uri, timestamp, nonce, body)
+
+	sig, err := signSHA256WithRSA(c.config.PrivateKey, []byte(message))
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf(`%s mchid="%s",nonce_str="%s",signature="%s",timestamp="%s",serial_no="%s"`,
+		authSchema, c.config.MchID, nonce, sig, timestamp, c.config.SerialNo), nil
+}
+
+// verify verifies the Wechatpay-* headers of a response or notification.
+func (c *V3Client) verify(header http.Header, body []byte) error {
+	if c.verifier == nil {
+		return errors.New("no verifier configured")
+	}
+
+	serialNo := header.Get("Wechatpay-Serial")
+	sig := header.Get("Wechatpay-Signature")
+	timestamp := header.Get("Wechatpay-Timestamp")
+	nonce := header.Get("Wechatpay-Nonce")
+	if serialNo == "" || sig == "" || timestamp == "" || nonce == "" {
+		return errors.New("signature headers missing")
+	}
+
+	message := fmt.Sprintf("%s\n%s\n%s\n", timestamp, nonce, body)
+	if err := c.verifier.Verify(serialNo, []byte(message), sig); err != nil {
+		return fmt.Errorf("signature failed, %v", err)
+	}
+	return nil
+}
+
+// doRequest encodes req in JSON, sends it to uri and decodes the verified
+// response into rsp. Either req or rsp may be nil. Sensitive fields are
+// encrypted in req and decrypted in rsp.
+func (c *V3Client) doRequest(method, uri string, req, rsp interface{}) error {
+	req, extra, err := c.encryptRequest(req)
+	if err != nil {
+		return err
+	}
+
+	header, data, err := c.send(method, uri, req, extra)
+	if err != nil {
+		return err
+	}
+
+	if err = c.verify(header, data); err != nil {
+		return err
+	}
+
+	if rsp == nil || len(data) == 0 {
+		return nil
+	}
+	if err = json.Unmarshal(data, rsp); err != nil {
+		return err
+	}
+	return c.decryptResponse(rsp)
+}
+
+// send signs and sends the request without verifying the response, returning
+// its headers and body. Responses other than 2xx are returned as *V3Error.
+func (c *V3Client) send(method, uri string, req interface{}, extra http.Header) (http.Header, []byte, error) {
+	var body []byte
+	if req != nil {
+		var err error
+		if body, err = json.Marshal(req); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	auth, err := c.authorization(method, uri, body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	httpReq, err := http.NewRequest(method, v3Host+uri, bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, err
+	}
+	for k, v := range extra {
+		httpReq.Header[k] = v
+	}
+	httpReq.Header.Set("Authorization", auth)
+	httpReq.Header.Set("Accept", "application/json")
+	if req != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+
+	httpRsp, err := c.client.Do(httpReq)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer httpRsp.Body.Close()
+
+	data, err := ioutil.ReadAll(httpRsp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if httpRsp.StatusCode < 200 || httpRsp.StatusCode > 299 {
+		return nil, nil, newV3Error(httpRsp, data)
+	}
+
+	return httpRsp.Header, data, nil
+}
+
+// newV3Error returns the *V3Error of a response other than 2xx with body.
+func newV3Error(rsp *http.Response, body []byte) *V3Error {
+	v3Err := &V3Error{
+		StatusCode: rsp.StatusCode,
+		RequestID:  rsp.Header.Get("Request-ID"),
+	}
+	if err := json.Unmarshal(body, v3Err); err != nil {
+		v3Err.Message = string(body)
+	}
+	return v3Err
+}
@@ -0,0 +1,174 @@
+package wx
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// v3TestEnv holds keys of a merchant and Weixin for tests of API v3.
+type v3TestEnv struct {
+	merchantKey *rsa.PrivateKey
+	platformKey *rsa.PrivateKey
+	platformCrt *x509.Certificate
+}
+
+func newV3TestEnv(t *testing.T) *v3TestEnv {
+	merchantKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	platformKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(0x5157F09EFDC096DE),
+		Subject:      pkix.Name{CommonName: "Tenpay.com Root CA"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &platformKey.PublicKey, platformKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &v3TestEnv{merchantKey: merchantKey, platformKey: platformKey, platformCrt: cert}
+}
+
+// client returns a *V3Client sending every request to srv.
+func (env *v3TestEnv) client(srv *httptest.Server) *V3Client {
+	c := NewV3Client(V3Config{
+		AppID:      "wxd678efh567hg6787",
+		MchID:      "1230000109",
+		SerialNo:   "1DDE55AD98ED71D6EDD4A4A16996DE7B47773A8C",
+		PrivateKey: env.merchantKey,
+		APIv3Key:   "0123456789abcdef0123456789abcdef",
+	})
+	c.WithVerifier(NewCertificateVerifier(env.platformCrt))
+	target, _ := url.Parse(srv.URL)
+	c.WithHTTPClient(&http.Client{Timeout: time.Second, Transport: &hostRewriter{target: target}})
+	return c
+}
+
+// sign sets the Wechatpay-* headers of body as Weixin does.
+func (env *v3TestEnv) sign(t *testing.T, header http.Header, body string) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	nonce := generateNonceStr()
+	sig, err := signSHA256WithRSA(env.platformKey, []byte(fmt.Sprintf("%s\n%s\n%s\n", timestamp, nonce, body)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	header.Set("Wechatpay-Serial", serialNumber(env.platformCrt))
+	header.Set("Wechatpay-Signature", sig)
+	header.Set("Wechatpay-Timestamp", timestamp)
+	header.Set("Wechatpay-Nonce", nonce)
+}
+
+var authPattern = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// checkAuthorization verifies the Authorization header of r with body.
+func (env *v3TestEnv) checkAuthorization(r *http.Request, body []byte) error {
+	auth := r.Header.Get("Authorization")
+	fields := map[string]string{}
+	for _, m := range authPattern.FindAllStringSubmatch(auth, -1) {
+		fields[m[1]] = m[2]
+	}
+	if fields["mchid"] != "1230000109" || fields["serial_no"] != "1DDE55AD98ED71D6EDD4A4A16996DE7B47773A8C" {
+		return fmt.Errorf("unexpected authorization %s", auth)
+	}
+
+	message := fmt.Sprintf("%s\n%s\n%s\n%s\n%s\n", r.Method, r.URL.RequestURI(), fields["timestamp"], fields["nonce_str"], body)
+	tmpl := &x509.Certificate{PublicKey: &env.merchantKey.PublicKey}
+	return verifySHA256WithRSA(tmpl, []byte(message), fields["signature"])
+}
+
+func TestV3ClientRequest(t *testing.T) {
+	env := newV3TestEnv(t)
+	tampered := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if err := env.checkAuthorization(r, body); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprintf(w, `{"code":"SIGN_ERROR","message":"%v"}`, err)
+			return
+		}
+		if string(body) != `{"mchid":"1230000109"}` {
+			w.Header().Set("Request-ID", "08F78BB5AF0610D302839A0518")
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"code":"PARAM_ERROR","message":"参数错误"}`)
+			return
+		}
+
+		rsp := `{"prepay_id":"wx26112221580621e9b071c00d9e093b0000"}`
+		env.sign(t, w.Header(), rsp)
+		if tampered {
+			rsp = `{"prepay_id":"wx0000000000000000000000000000000000"}`
+		}
+		fmt.Fprint(w, rsp)
+	}))
+	defer srv.Close()
+
+	c := env.client(srv)
+	req := map[string]string{"mchid": "1230000109"}
+	rsp := struct {
+		PrepayID string `json:"prepay_id"`
+	}{}
+	if err := c.doRequest(http.MethodPost, "/v3/pay/transactions/jsapi?x=1", req, &rsp); err != nil {
+		t.Fatal(err)
+	}
+	if rsp.PrepayID != "wx26112221580621e9b071c00d9e093b0000" {
+		t.Errorf("returned: %s", rsp.PrepayID)
+	}
+
+	tampered = true
+	if err := c.doRequest(http.MethodPost, "/v3/pay/transactions/jsapi", req, &rsp); err == nil {
+		t.Error("expected signature failure with tampered response")
+	}
+
+	err := c.doRequest(http.MethodPost, "/v3/pay/transactions/jsapi", map[string]string{}, &rsp)
+	var v3Err *V3Error
+	if !errors.As(err, &v3Err) {
+		t.Fatalf("returned: %v, expected: *V3Error", err)
+	}
+	if v3Err.StatusCode != http.StatusBadRequest || v3Err.Code != CodeParamError || v3Err.RequestID != "08F78BB5AF0610D302839A0518" {
+		t.Errorf("returned: %#v", v3Err)
+	}
+}
+
+func TestV3ClientTimeout(t *testing.T) {
+	if c := NewV3Client(V3Config{}); c.client.Timeout != defaultV3Timeout {
+		t.Errorf("returned: %v, expected: %v", c.client.Timeout, defaultV3Timeout)
+	}
+
+	env := newV3TestEnv(t)
+	done := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer srv.Close()
+	defer close(done)
+
+	c := env.client(srv)
+	c.client.Timeout = 50 * time.Millisecond
+	if _, err := c.QueryTransaction("1217752501201407033233368018"); err == nil {
+		t.Error("expected timeout with server not answering")
+	}
+}
@@ -0,0 +1,239 @@
+package wx
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// maxSubOrders is the maximum number of sub-orders in a combined payment.
+const maxSubOrders = 50
+
+// CombineTransactionOptions contains fields of the combined payment order of
+// sub-orders, amounts are in fen.
+type CombineTransactionOptions struct {
+	CombineOutTradeNo string            `json:"combine_out_trade_no"`         // 合单商户订单号
+	SceneInfo         *V3SceneInfo      `json:"scene_info,omitempty"`         // 场景信息，H5必填
+	SubOrders         []SubOrderOptions `json:"sub_orders"`                   // 子单信息，最多50单
+	CombinePayerInfo  *Payer            `json:"combine_payer_info,omitempty"` // 支付者，JSAPI必填
+	TimeStart         time.Time         `json:"-"`                            // 交易起始时间
+	TimeExpire        time.Time         `json:"-"`                            // 交易结束时间
+}
+
+// SubOrderOptions is a sub-order of combined payment. MchID is that of the
+// client if empty.
+type SubOrderOptions struct {
+	MchID       string             `json:"mchid"`                 // 子单商户号
+	SubMchID    string             `json:"sub_mchid,omitempty"`   // 二级商户号
+	SubAppID    string             `json:"sub_appid,omitempty"`   // 子商户应用ID
+	OutTradeNo  string             `json:"out_trade_no"`          // 子单商户订单号
+	Description string             `json:"description"`           // 商品描述
+	Attach      string             `json:"attach"`                // 附加数据
+	GoodsTag    string             `json:"goods_tag,omitempty"`   // 订单优惠标记
+	Amount      CombineAmount      `json:"amount"`                // 订单金额
+	SettleInfo  *CombineSettleInfo `json:"settle_info,omitempty"` // 结算信息
+}
+
+// CombineAmount is the amount of sub-order in fen.
+type CombineAmount struct {
+	TotalAmount int    `json:"total_amount"` // 标价金额
+	Currency    string `json:"currency"`     // 标价币种，CNY
+}
+
+// CombineSettleInfo is the settlement of sub-order.
+type CombineSettleInfo struct {
+	ProfitSharing bool `json:"profit_sharing,omitempty"` // 是否指定分账
+	SubsidyAmount int  `json:"subsidy_amount,omitempty"` // 补差金额
+}
+
+func (opts CombineTransactionOptions) validate() error {
+	if !outTradeNoPattern.MatchString(opts.CombineOutTradeNo) {
+		return fmt.Errorf("invalid combine_out_trade_no %q, 1 to 32 letters, digits or _-|* required", opts.CombineOutTradeNo)
+	}
+
+	if len(opts.SubOrders) == 0 || len(opts.SubOrders) > maxSubOrders {
+		return fmt.Errorf("invalid %d sub orders, 1 to %d required", len(opts.SubOrders), maxSubOrders)
+	}
+
+	for _, order := range opts.SubOrders {
+		if !outTradeNoPattern.MatchString(order.OutTradeNo) {
+			return fmt.Errorf("invalid out_trade_no %q of sub order", order.OutTradeNo)
+		}
+		if order.Amount.TotalAmount <= 0 {
+			return fmt.Errorf("invalid total_amount %d of sub order %s", order.Amount.TotalAmount, order.OutTradeNo)
+		}
+	}
+
+	return nil
+}
+
+// CombinePayJSAPI creates a combined payment order for 公众号支付、小程序支付,
+// opts.CombinePayerInfo is required.
+func (c *V3Client) CombinePayJSAPI(opts CombineTransactionOptions) (*PrepayRsp, error) {
+	if opts.CombinePayerInfo == nil || opts.CombinePayerInfo.OpenID == "" {
+		return nil, errors.New("combine_payer_info openid required by JSAPI")
+	}
+	return c.combinePrepay("/v3/combine-transactions/jsapi", opts)
+}
+
+// CombinePayApp creates a combined payment order for APP支付.
+func (c *V3Client) CombinePayApp(opts CombineTransactionOptions) (*PrepayRsp, error) {
+	return c.combinePrepay("/v3/combine-transactions/app", opts)
+}
+
+// CombinePayH5 creates a combined payment order for H5支付, opts.SceneInfo
+// with H5Info is required.
+func (c *V3Client) CombinePayH5(opts CombineTransactionOptions) (*PrepayRsp, error) {
+	if opts.SceneInfo == nil || opts.SceneInfo.H5Info == nil || opts.SceneInfo.PayerClientIP == "" {
+		return nil, errors.New("scene_info with payer_client_ip and h5_info required by H5")
+	}
+	return c.combinePrepay("/v3/combine-transactions/h5", opts)
+}
+
+// CombinePayNative creates a combined payment order for Native支付.
+func (c *V3Client) CombinePayNative(opts CombineTransactionOptions) (*PrepayRsp, error) {
+	return c.combinePrepay("/v3/combine-transactions/native", opts)
+}
+
+func (c *V3Client) combinePrepay(uri string, opts CombineTransactionOptions) (*PrepayRsp, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	// a copy, so that the sub-orders of opts are left unchanged
+	subOrders := make([]SubOrderOptions, len(opts.SubOrders))
+	copy(subOrders, opts.SubOrders)
+	for i := range subOrders {
+		if subOrders[i].MchID == "" {
+			subOrders[i].MchID = c.config.MchID
+		}
+	}
+	opts.SubOrders = subOrders
+
+	req := combineTransactionReq{
+		CombineAppID:              c.config.AppID,
+		CombineMchID:              c.config.MchID,
+		NotifyURL:                 c.config.NotifyURL,
+		CombineTransactionOptions: opts,
+	}
+	if !opts.TimeStart.IsZero() {
+		req.TimeStart = opts.TimeStart.Format(time.RFC3339)
+	}
+	if !opts.TimeExpire.IsZero() {
+		req.TimeExpire = opts.TimeExpire.Format(time.RFC3339)
+	}
+
+	rsp := &PrepayRsp{}
+	if err := c.doRequest(http.MethodPost, uri, req, rsp); err != nil {
+		return nil, err
+	}
+	return rsp, nil
+}
+
+// QueryCombineTransaction queries the combined payment order with the
+// results of its sub-orders.
+func (c *V3Client) QueryCombineTransaction(combineOutTradeNo string) (*CombineTransaction, error) {
+	rsp := &CombineTransaction{}
+	uri := "/v3/combine-transactions/out-trade-no/" + url.PathEscape(combineOutTradeNo)
+	if err := c.doRequest(http.MethodGet, uri, nil, rsp); err != nil {
+		return nil, err
+	}
+	return rsp, nil
+}
+
+// CloseSubOrder identifies a sub-order to close. MchID is that of the client
+// if empty.
+type CloseSubOrder struct {
+	MchID      string `json:"mchid"`               // 子单商户号
+	OutTradeNo string `json:"out_trade_no"`        // 子单商户订单号
+	SubMchID   string `json:"sub_mchid,omitempty"` // 二级商户号
+	SubAppID   string `json:"sub_appid,omitempty"` // 子商户应用ID
+}
+
+// CloseCombineTransaction closes the unpaid combined payment order with all
+// its sub-orders.
+func (c *V3Client) CloseCombineTransaction(combineOutTradeNo string, subOrders []CloseSubOrder) error {
+	req := closeCombineTransactionReq{CombineAppID: c.config.AppID}
+	for _, order := range subOrders {
+		if order.MchID == "" {
+			order.MchID = c.config.MchID
+		}
+		req.SubOrders = append(req.SubOrders, order)
+	}
+
+	uri := "/v3/combine-transactions/out-trade-no/" + url.PathEscape(combineOutTradeNo) + "/close"
+	return c.doRequest(http.MethodPost, uri, req, nil)
+}
+
+// CombineTransaction decodes Plaintext of TRANSACTION.* events of combined
+// payments.
+func (n *V3Notification) CombineTransaction() (*CombineTransaction, error) {
+	result := &CombineTransaction{}
+	if err := n.Decode(result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+type combineTransactionReq struct {
+	CombineAppID string `json:"combine_appid"`         // 合单发起方的appid
+	CombineMchID string `json:"combine_mchid"`         // 合单发起方商户号
+	NotifyURL    string `json:"notify_url"`            // 通知地址
+	TimeStart    string `json:"time_start,omitempty"`  // 交易起始时间
+	TimeExpire   string `json:"time_expire,omitempty"` // 交易结束时间
+	CombineTransactionOptions
+}
+
+type closeCombineTransactionReq struct {
+	CombineAppID string          `json:"combine_appid"` // 合单发起方的appid
+	SubOrders    []CloseSubOrder `json:"sub_orders"`    // 子单信息
+}
+
+// CombineTransaction is the combined payment order with results of its
+// sub-orders.
+type CombineTransaction struct {
+	CombineAppID      string                `json:"combine_appid"`                // 合单发起方的appid
+	CombineMchID      string                `json:"combine_mchid"`                // 合单发起方商户号
+	CombineOutTradeNo string                `json:"combine_out_trade_no"`         // 合单商户订单号
+	SceneInfo         *TransactionSceneInfo `json:"scene_info,omitempty"`         // 场景信息
+	SubOrders         []SubOrder            `json:"sub_orders"`                   // 子单信息
+	CombinePayerInfo  *Payer                `json:"combine_payer_info,omitempty"` // 支付者
+}
+
+// SubOrder returns the sub-order of outTradeNo.
+func (t *CombineTransaction) SubOrder(outTradeNo string) (*SubOrder, bool) {
+	for i := range t.SubOrders {
+		if t.SubOrders[i].OutTradeNo == outTradeNo {
+			return &t.SubOrders[i], true
+		}
+	}
+	return nil, false
+}
+
+// SubOrder is the result of a sub-order in combined payment.
+type SubOrder struct {
+	MchID           string            `json:"mchid"`                      // 子单商户号
+	TradeType       string            `json:"trade_type"`                 // 交易类型
+	TradeState      string            `json:"trade_state"`                // 交易状态
+	BankType        string            `json:"bank_type"`                  // 付款银行
+	Attach          string            `json:"attach"`                     // 附加数据
+	SuccessTime     string            `json:"success_time"`               // 支付完成时间
+	TransactionID   string            `json:"transaction_id"`             // 微信订单号
+	OutTradeNo      string            `json:"out_trade_no"`               // 子单商户订单号
+	SubMchID        string            `json:"sub_mchid"`                  // 二级商户号
+	SubAppID        string            `json:"sub_appid"`                  // 子商户应用ID
+	SubOpenID       string            `json:"sub_openid"`                 // 子商户用户标识
+	Amount          SubOrderAmount    `json:"amount"`                     // 订单金额
+	PromotionDetail []PromotionDetail `json:"promotion_detail,omitempty"` // 优惠功能
+}
+
+// SubOrderAmount is the amount of sub-order paid in fen.
+type SubOrderAmount struct {
+	TotalAmount    int    `json:"total_amount"`    // 标价金额
+	PayerAmount    int    `json:"payer_amount"`    // 现金支付金额
+	Currency       string `json:"currency"`        // 标价币种
+	PayerCurrency  string `json:"payer_currency"`  // 现金支付币种
+	SettlementRate int    `json:"settlement_rate"` // 结算汇率
+}
@@ -0,0 +1,79 @@
+package wx
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCombinePayApp(t *testing.T) {
+	env := newV3TestEnv(t)
+	var received combineTransactionReq
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(body, &received)
+		rsp := `{"prepay_id":"wx201410272009395522657a690389285100"}`
+		env.sign(t, w.Header(), rsp)
+		fmt.Fprint(w, rsp)
+	}))
+	defer srv.Close()
+
+	c := env.client(srv)
+	opts := CombineTransactionOptions{
+		CombineOutTradeNo: "P20150806125346",
+		SubOrders: []SubOrderOptions{
+			{SubMchID: "1900000109", OutTradeNo: "20150806125346", Description: "腾讯充值中心-QQ会员充值", Attach: "深圳分店", Amount: CombineAmount{TotalAmount: 10, Currency: "CNY"}},
+			{SubMchID: "1900000110", OutTradeNo: "20150806125347", Description: "腾讯充值中心-QQ会员充值", Attach: "广州分店", Amount: CombineAmount{TotalAmount: 20, Currency: "CNY"}, SettleInfo: &CombineSettleInfo{ProfitSharing: true}},
+		},
+	}
+
+	rsp, err := c.CombinePayApp(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rsp.PrepayID != "wx201410272009395522657a690389285100" {
+		t.Errorf("returned: %s", rsp.PrepayID)
+	}
+	if received.CombineMchID != "1230000109" || len(received.SubOrders) != 2 || received.SubOrders[1].MchID != "1230000109" {
+		t.Errorf("returned: %#v", received)
+	}
+	if opts.SubOrders[0].MchID != "" {
+		t.Error("expected options to be left unchanged")
+	}
+
+	opts.SubOrders[1].Amount.TotalAmount = 0
+	if _, err = c.CombinePayApp(opts); err == nil {
+		t.Error("expected failure with zero amount")
+	}
+}
+
+func TestCombineTransactionNotify(t *testing.T) {
+	env := newV3TestEnv(t)
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+	c := env.client(srv)
+
+	plain := `{"combine_appid":"wxd678efh567hg6787","combine_mchid":"1230000109","combine_out_trade_no":"P20150806125346",` +
+		`"sub_orders":[{"mchid":"1230000109","trade_state":"SUCCESS","out_trade_no":"20150806125346","sub_mchid":"1900000109",` +
+		`"amount":{"total_amount":10,"payer_amount":10,"currency":"CNY","payer_currency":"CNY"}}],` +
+		`"combine_payer_info":{"openid":"oUpF8uMuAJO_M2pxb1Q9zNjWeS6o"}}`
+	n, err := c.ParseNotify(env.newNotifyRequest(t, EventTransactionSuccess, plain))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	trans, err := n.CombineTransaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	order, ok := trans.SubOrder("20150806125346")
+	if !ok {
+		t.Fatal("expected sub order 20150806125346")
+	}
+	if order.TradeState != TradeStateSuccess || order.Amount.PayerAmount != 10 || order.SubMchID != "1900000109" {
+		t.Errorf("returned: %#v", order)
+	}
+}
@@ -0,0 +1,196 @@
+package wx
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// notifyMaxSkew is the maximum difference between the time of notifications
+// and now, older notifications are rejected as replayed.
+const notifyMaxSkew = 5 * time.Minute
+
+// constants for event type of notifications.
+const (
+	EventTransactionSuccess = "TRANSACTION.SUCCESS" // 支付成功
+	EventRefundSuccess      = "REFUND.SUCCESS"      // 退款成功
+	EventRefundAbnormal     = "REFUND.ABNORMAL"     // 退款异常
+	EventRefundClosed       = "REFUND.CLOSED"       // 退款关闭
+)
+
+// constants for trade state.
+const (
+	TradeStateSuccess    = "SUCCESS"    // 支付成功
+	TradeStateRefund     = "REFUND"     // 转入退款
+	TradeStateNotPay     = "NOTPAY"     // 未支付
+	TradeStateClosed     = "CLOSED"     // 已关闭
+	TradeStateRevoked    = "REVOKED"    // 已撤销（付款码支付）
+	TradeStateUserPaying = "USERPAYING" // 用户支付中（付款码支付）
+	TradeStatePayError   = "PAYERROR"   // 支付失败
+)
+
+// V3Notification is a verified notification of API v3, Plaintext is the
+// resource decrypted.
+type V3Notification struct {
+	ID           string            `json:"id"`            // 通知ID
+	CreateTime   string            `json:"create_time"`   // 通知创建时间
+	EventType    string            `json:"event_type"`    // 通知类型
+	ResourceType string            `json:"resource_type"` // 通知数据类型
+	Resource     EncryptedResource `json:"resource"`      // 通知数据
+	Summary      string            `json:"summary"`       // 回调摘要
+	Plaintext    []byte            `json:"-"`
+}
+
+// Decode decodes Plaintext into v, for events other than those of payment and
+// refund.
+func (n *V3Notification) Decode(v interface{}) error {
+	return json.Unmarshal(n.Plaintext, v)
+}
+
+// Transaction decodes Plaintext of TRANSACTION.* events.
+func (n *V3Notification) Transaction() (*Transaction, error) {
+	result := &Transaction{}
+	if err := n.Decode(result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Refund decodes Plaintext of REFUND.* events.
+func (n *V3Notification) Refund() (*RefundNotification, error) {
+	result := &RefundNotification{}
+	if err := n.Decode(result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ParseNotify verifies the notification of API v3 from Weixin, rejecting
+// stale ones, and decrypts its resource with the APIv3 key.
+func (c *V3Client) ParseNotify(req *http.Request) (*V3Notification, error) {
+	data, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	timestamp, err := strconv.ParseInt(req.Header.Get("Wechatpay-Timestamp"), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timestamp %q", req.Header.Get("Wechatpay-Timestamp"))
+	}
+	skew := time.Since(time.Unix(timestamp, 0))
+	if skew > notifyMaxSkew || skew < -notifyMaxSkew {
+		return nil, fmt.Errorf("stale timestamp %d", timestamp)
+	}
+
+	if err = c.verify(req.Header, data); err != nil {
+		return nil, err
+	}
+
+	n := &V3Notification{}
+	if err = json.Unmarshal(data, n); err != nil {
+		return nil, err
+	}
+
+	n.Plaintext, err = decryptAEADAES256GCM(n.Resource, c.config.APIv3Key)
+	if err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// AnswerNotify answers the notification, a non-nil err makes Weixin notify
+// again later.
+func (c *V3Client) AnswerNotify(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		data, _ := json.Marshal(map[string]string{"code": "FAIL", "message": err.Error()})
+		w.Write(data)
+		return
+	}
+	w.Write([]byte(`{"code":"SUCCESS","message":"成功"}`))
+}
+
+// Transaction is the payment order of API v3.
+type Transaction struct {
+	AppID           string                `json:"appid"`                      // 应用ID
+	MchID           string                `json:"mchid"`                      // 商户号
+	OutTradeNo      string                `json:"out_trade_no"`               // 商户订单号
+	TransactionID   string                `json:"transaction_id"`             // 微信支付订单号
+	TradeType       string                `json:"trade_type"`                 // 交易类型
+	TradeState      string                `json:"trade_state"`                // 交易状态
+	TradeStateDesc  string                `json:"trade_state_desc"`           // 交易状态描述
+	BankType        string                `json:"bank_type"`                  // 付款银行
+	Attach          string                `json:"attach"`                     // 附加数据
+	SuccessTime     string                `json:"success_time"`               // 支付完成时间
+	Payer           *Payer                `json:"payer,omitempty"`            // 支付者
+	Amount          *TransactionAmount    `json:"amount,omitempty"`           // 订单金额
+	SceneInfo       *TransactionSceneInfo `json:"scene_info,omitempty"`       // 场景信息
+	PromotionDetail []PromotionDetail     `json:"promotion_detail,omitempty"` // 优惠功能
+}
+
+// Payer is the user paying.
+type Payer struct {
+	OpenID string `json:"openid"` // 用户标识
+}
+
+// TransactionAmount is the amount of payment order in fen.
+type TransactionAmount struct {
+	Total         int    `json:"total"`          // 总金额
+	PayerTotal    int    `json:"payer_total"`    // 用户支付金额
+	Currency      string `json:"currency"`       // 货币类型
+	PayerCurrency string `json:"payer_currency"` // 用户支付币种
+}
+
+// TransactionSceneInfo is the scene of payment order.
+type TransactionSceneInfo struct {
+	DeviceID string `json:"device_id"` // 商户端设备号
+}
+
+// PromotionDetail is a promotion applied to payment order.
+type PromotionDetail struct {
+	CouponID            string                 `json:"coupon_id"`              // 券ID
+	Name                string                 `json:"name"`                   // 优惠名称
+	Scope               string                 `json:"scope"`                  // 优惠范围
+	Type                string                 `json:"type"`                   // 优惠类型
+	Amount              int                    `json:"amount"`                 // 优惠券面额
+	StockID             string                 `json:"stock_id"`               // 活动ID
+	WechatpayContribute int                    `json:"wechatpay_contribute"`   // 微信出资
+	MerchantContribute  int                    `json:"merchant_contribute"`    // 商户出资
+	OtherContribute     int                    `json:"other_contribute"`       // 其他出资
+	Currency            string                 `json:"currency"`               // 优惠币种
+	GoodsDetail         []PromotionGoodsDetail `json:"goods_detail,omitempty"` // 单品列表
+}
+
+// PromotionGoodsDetail is a goods of promotion.
+type PromotionGoodsDetail struct {
+	GoodsID        string `json:"goods_id"`        // 商品编码
+	Quantity       int    `json:"quantity"`        // 商品数量
+	UnitPrice      int    `json:"unit_price"`      // 商品单价
+	DiscountAmount int    `json:"discount_amount"` // 商品优惠金额
+	GoodsRemark    string `json:"goods_remark"`    // 商品备注
+}
+
+// RefundNotification is the resource of REFUND.* events.
+type RefundNotification struct {
+	MchID               string                   `json:"mchid"`                 // 直连商户号
+	OutTradeNo          string                   `json:"out_trade_no"`          // 商户订单号
+	TransactionID       string                   `json:"transaction_id"`        // 微信支付订单号
+	OutRefundNo         string                   `json:"out_refund_no"`         // 商户退款单号
+	RefundID            string                   `json:"refund_id"`             // 微信支付退款单号
+	RefundStatus        RefundStatus             `json:"refund_status"`         // 退款状态
+	SuccessTime         string                   `json:"success_time"`          // 退款成功时间
+	UserReceivedAccount string                   `json:"user_received_account"` // 退款入账账户
+	Amount              RefundNotificationAmount `json:"amount"`                // 金额信息
+}
+
+// RefundNotificationAmount is the amount of refund in fen.
+type RefundNotificationAmount struct {
+	Total       int `json:"total"`        // 订单金额
+	Refund      int `json:"refund"`       // 退款金额
+	PayerTotal  int `json:"payer_total"`  // 用户支付金额
+	PayerRefund int `json:"payer_refund"` // 用户退款金额
+}
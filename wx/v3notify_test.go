@@ -0,0 +1,86 @@
+package wx
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newNotifyRequest returns a notification of event with plain encrypted and
+// signed by env.
+func (env *v3TestEnv) newNotifyRequest(t *testing.T, event, plain string) *http.Request {
+	body, _ := json.Marshal(V3Notification{
+		ID:           "EV-2018022511223320873",
+		CreateTime:   "2015-05-20T13:29:35+08:00",
+		EventType:    event,
+		ResourceType: "encrypt-resource",
+		Resource:     encryptResource(t, plain, "0123456789abcdef0123456789abcdef", "transaction"),
+		Summary:      "支付成功",
+	})
+	req := httptest.NewRequest(http.MethodPost, "/notify", strings.NewReader(string(body)))
+	env.sign(t, req.Header, string(body))
+	return req
+}
+
+func TestParseNotify(t *testing.T) {
+	env := newV3TestEnv(t)
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+	c := env.client(srv)
+
+	plain := `{"mchid":"1230000109","appid":"wxd678efh567hg6787","out_trade_no":"1217752501201407033233368018",` +
+		`"transaction_id":"1217752501201407033233368018","trade_type":"JSAPI","trade_state":"SUCCESS",` +
+		`"payer":{"openid":"oUpF8uMuAJO_M2pxb1Q9zNjWeS6o"},"amount":{"total":100,"payer_total":90,"currency":"CNY","payer_currency":"CNY"},` +
+		`"promotion_detail":[{"coupon_id":"109519","amount":10,"goods_detail":[{"goods_id":"M1006","quantity":1,"unit_price":100}]}]}`
+	n, err := c.ParseNotify(env.newNotifyRequest(t, EventTransactionSuccess, plain))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.EventType != EventTransactionSuccess {
+		t.Errorf("returned: %s", n.EventType)
+	}
+
+	trans, err := n.Transaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trans.TradeState != TradeStateSuccess || trans.Payer.OpenID != "oUpF8uMuAJO_M2pxb1Q9zNjWeS6o" || trans.Amount.PayerTotal != 90 {
+		t.Errorf("returned: %#v", trans)
+	}
+	if len(trans.PromotionDetail) != 1 || trans.PromotionDetail[0].GoodsDetail[0].GoodsID != "M1006" {
+		t.Errorf("returned: %#v", trans.PromotionDetail)
+	}
+
+	stale := env.newNotifyRequest(t, EventTransactionSuccess, plain)
+	stale.Header.Set("Wechatpay-Timestamp", strconv.FormatInt(time.Now().Add(-10*time.Minute).Unix(), 10))
+	if _, err = c.ParseNotify(stale); err == nil {
+		t.Error("expected failure with stale timestamp")
+	}
+
+	forged := env.newNotifyRequest(t, EventTransactionSuccess, plain)
+	forged.Header.Set("Wechatpay-Nonce", "forged")
+	if _, err = c.ParseNotify(forged); err == nil {
+		t.Error("expected signature failure with forged nonce")
+	}
+}
+
+func TestAnswerNotify(t *testing.T) {
+	c := NewV3Client(V3Config{})
+
+	w := httptest.NewRecorder()
+	c.AnswerNotify(w, nil)
+	if w.Code != http.StatusOK || w.Body.String() != `{"code":"SUCCESS","message":"成功"}` {
+		t.Errorf("returned: %d %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	c.AnswerNotify(w, errors.New("order not found"))
+	if w.Code != http.StatusInternalServerError || w.Body.String() != `{"code":"FAIL","message":"order not found"}` {
+		t.Errorf("returned: %d %s", w.Code, w.Body.String())
+	}
+}
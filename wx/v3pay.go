@@ -0,0 +1,231 @@
+package wx
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// TransactionOptions contains fields of the payment order in API v3, amounts
+// are in fen.
+type TransactionOptions struct {
+	Description   string         `json:"description"`              // 商品描述
+	OutTradeNo    string         `json:"out_trade_no"`             // 商户订单号
+	TimeExpire    time.Time      `json:"-"`                        // 交易结束时间
+	Attach        string         `json:"attach,omitempty"`         // 附加数据
+	GoodsTag      string         `json:"goods_tag,omitempty"`      // 订单优惠标记
+	SupportFapiao bool           `json:"support_fapiao,omitempty"` // 电子发票入口开放标识
+	Amount        OrderAmount    `json:"amount"`                   // 订单金额
+	Payer         *Payer         `json:"payer,omitempty"`          // 支付者，JSAPI必填
+	Detail        *V3OrderDetail `json:"detail,omitempty"`         // 优惠功能
+	SceneInfo     *V3SceneInfo   `json:"scene_info,omitempty"`     // 场景信息，H5必填
+	SettleInfo    *SettleInfo    `json:"settle_info,omitempty"`    // 结算信息
+}
+
+func (opts TransactionOptions) validate() error {
+	if opts.Description == "" || len(opts.Description) > 127 {
+		return fmt.Errorf("invalid description %q, 1 to 127 bytes required", opts.Description)
+	}
+
+	if !outTradeNoPattern.MatchString(opts.OutTradeNo) {
+		return fmt.Errorf("invalid out_trade_no %q, 1 to 32 letters, digits or _-|* required", opts.OutTradeNo)
+	}
+
+	if opts.Amount.Total <= 0 {
+		return fmt.Errorf("invalid amount total %d, positive fen required", opts.Amount.Total)
+	}
+
+	if !opts.TimeExpire.IsZero() && opts.TimeExpire.Before(time.Now()) {
+		return errors.New("time_expire must be in the future")
+	}
+
+	return nil
+}
+
+// OrderAmount is the amount of order in fen.
+type OrderAmount struct {
+	Total    int    `json:"total"`              // 总金额
+	Currency string `json:"currency,omitempty"` // 货币类型，CNY by default
+}
+
+// V3OrderDetail is the detail of goods for 单品优惠 in API v3.
+type V3OrderDetail struct {
+	CostPrice   int            `json:"cost_price,omitempty"` // 订单原价
+	InvoiceID   string         `json:"invoice_id,omitempty"` // 商品小票ID
+	GoodsDetail []V3OrderGoods `json:"goods_detail"`         // 单品列表
+}
+
+// V3OrderGoods is a goods of the order in API v3.
+type V3OrderGoods struct {
+	MerchantGoodsID  string `json:"merchant_goods_id"`            // 商户侧商品编码
+	WechatpayGoodsID string `json:"wechatpay_goods_id,omitempty"` // 微信支付商品编码
+	GoodsName        string `json:"goods_name,omitempty"`         // 商品名称
+	Quantity         int    `json:"quantity"`                     // 商品数量
+	UnitPrice        int    `json:"unit_price"`                   // 商品单价
+}
+
+// V3SceneInfo describes where the order is created in API v3.
+type V3SceneInfo struct {
+	PayerClientIP string     `json:"payer_client_ip"`      // 用户终端IP
+	DeviceID      string     `json:"device_id,omitempty"`  // 商户端设备号
+	StoreInfo     *StoreInfo `json:"store_info,omitempty"` // 商户门店信息
+	H5Info        *V3H5Info  `json:"h5_info,omitempty"`    // H5场景信息
+}
+
+// V3H5Info is the website or App paying with H5 in API v3.
+type V3H5Info struct {
+	Type        string `json:"type"`                   // 场景类型，iOS、Android或Wap
+	AppName     string `json:"app_name,omitempty"`     // 应用名称
+	AppURL      string `json:"app_url,omitempty"`      // 网站URL
+	BundleID    string `json:"bundle_id,omitempty"`    // iOS平台BundleID
+	PackageName string `json:"package_name,omitempty"` // Android平台PackageName
+}
+
+// SettleInfo is the settlement of order.
+type SettleInfo struct {
+	ProfitSharing bool `json:"profit_sharing"` // 是否指定分账
+}
+
+// PayJSAPI creates a payment order for 公众号支付、小程序支付, opts.Payer is
+// required.
+func (c *V3Client) PayJSAPI(opts TransactionOptions) (*PrepayRsp, error) {
+	if opts.Payer == nil || opts.Payer.OpenID == "" {
+		return nil, errors.New("payer openid required by JSAPI")
+	}
+	return c.prepay("/v3/pay/transactions/jsapi", opts)
+}
+
+// PayApp creates a payment order for APP支付.
+func (c *V3Client) PayApp(opts TransactionOptions) (*PrepayRsp, error) {
+	return c.prepay("/v3/pay/transactions/app", opts)
+}
+
+// PayH5 creates a payment order for H5支付, opts.SceneInfo with H5Info is
+// required.
+func (c *V3Client) PayH5(opts TransactionOptions) (*PrepayRsp, error) {
+	if opts.SceneInfo == nil || opts.SceneInfo.H5Info == nil || opts.SceneInfo.PayerClientIP == "" {
+		return nil, errors.New("scene_info with payer_client_ip and h5_info required by H5")
+	}
+	return c.prepay("/v3/pay/transactions/h5", opts)
+}
+
+// PayNative creates a payment order for Native支付.
+func (c *V3Client) PayNative(opts TransactionOptions) (*PrepayRsp, error) {
+	return c.prepay("/v3/pay/transactions/native", opts)
+}
+
+func (c *V3Client) prepay(uri string, opts TransactionOptions) (*PrepayRsp, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	req := transactionReq{
+		AppID:              c.config.AppID,
+		MchID:              c.config.MchID,
+		NotifyURL:          c.config.NotifyURL,
+		TransactionOptions: opts,
+	}
+	if !opts.TimeExpire.IsZero() {
+		req.TimeExpire = opts.TimeExpire.Format(time.RFC3339)
+	}
+
+	rsp := &PrepayRsp{}
+	if err := c.doRequest(http.MethodPost, uri, req, rsp); err != nil {
+		return nil, err
+	}
+	return rsp, nil
+}
+
+// QueryTransaction queries the payment order by transaction_id.
+func (c *V3Client) QueryTransaction(transactionID string) (*Transaction, error) {
+	return c.queryTransaction("/v3/pay/transactions/id/" + url.PathEscape(transactionID))
+}
+
+// QueryTransactionByOutTradeNo queries the payment order by out_trade_no.
+func (c *V3Client) QueryTransactionByOutTradeNo(outTradeNo string) (*Transaction, error) {
+	return c.queryTransaction("/v3/pay/transactions/out-trade-no/" + url.PathEscape(outTradeNo))
+}
+
+func (c *V3Client) queryTransaction(uri string) (*Transaction, error) {
+	rsp := &Transaction{}
+	if err := c.doRequest(http.MethodGet, uri+"?mchid="+url.QueryEscape(c.config.MchID), nil, rsp); err != nil {
+		return nil, err
+	}
+	return rsp, nil
+}
+
+// CloseTransaction closes the unpaid order of outTradeNo.
+func (c *V3Client) CloseTransaction(outTradeNo string) error {
+	uri := "/v3/pay/transactions/out-trade-no/" + url.PathEscape(outTradeNo) + "/close"
+	return c.doRequest(http.MethodPost, uri, map[string]string{"mchid": c.config.MchID}, nil)
+}
+
+// JSAPIPayment is passed to WeixinJSBridge.invoke or wx.requestPayment.
+type JSAPIPayment struct {
+	AppID     string `json:"appId"`
+	TimeStamp string `json:"timeStamp"`
+	NonceStr  string `json:"nonceStr"`
+	Package   string `json:"package"`
+	SignType  string `json:"signType"`
+	PaySign   string `json:"paySign"`
+}
+
+// ToJSAPIPayment returns JSAPIPayment from prepayID, signed by the merchant
+// private key.
+func (c *V3Client) ToJSAPIPayment(prepayID string) (*JSAPIPayment, error) {
+	payment := &JSAPIPayment{
+		AppID:     c.config.AppID,
+		TimeStamp: strconv.FormatInt(time.Now().Unix(), 10),
+		NonceStr:  generateNonceStr(),
+		Package:   "prepay_id=" + prepayID,
+		SignType:  "RSA",
+	}
+
+	message := fmt.Sprintf("%s\n%s\n%s\n%s\n", payment.AppID, payment.TimeStamp, payment.NonceStr, payment.Package)
+	sig, err := signSHA256WithRSA(c.config.PrivateKey, []byte(message))
+	if err != nil {
+		return nil, err
+	}
+	payment.PaySign = sig
+	return payment, nil
+}
+
+// ToAppPayment returns Payment to App from prepayID, signed by the merchant
+// private key.
+func (c *V3Client) ToAppPayment(prepayID string) (*Payment, error) {
+	payment := &Payment{
+		AppID:     c.config.AppID,
+		PartnerID: c.config.MchID,
+		PrepayID:  prepayID,
+		NonceStr:  generateNonceStr(),
+		Timestamp: strconv.FormatInt(time.Now().Unix(), 10),
+		Package:   "Sign=WXPay",
+	}
+
+	message := fmt.Sprintf("%s\n%s\n%s\n%s\n", payment.AppID, payment.Timestamp, payment.NonceStr, payment.PrepayID)
+	sig, err := signSHA256WithRSA(c.config.PrivateKey, []byte(message))
+	if err != nil {
+		return nil, err
+	}
+	payment.Sign = sig
+	return payment, nil
+}
+
+type transactionReq struct {
+	AppID      string `json:"appid"`                 // 应用ID
+	MchID      string `json:"mchid"`                 // 直连商户号
+	NotifyURL  string `json:"notify_url"`            // 通知地址
+	TimeExpire string `json:"time_expire,omitempty"` // 交易结束时间
+	TransactionOptions
+}
+
+// PrepayRsp is the response returned by /v3/pay/transactions/*, with
+// PrepayID for JSAPI and App, H5URL for H5 and CodeURL for Native.
+type PrepayRsp struct {
+	PrepayID string `json:"prepay_id"` // 预支付交易会话标识
+	H5URL    string `json:"h5_url"`    // 支付跳转链接
+	CodeURL  string `json:"code_url"`  // 二维码链接
+}
@@ -0,0 +1,104 @@
+package wx
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPayJSAPI(t *testing.T) {
+	env := newV3TestEnv(t)
+	var received map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if err := env.checkAuthorization(r, body); err != nil {
+			t.Error(err)
+		}
+
+		var rsp string
+		switch r.URL.RequestURI() {
+		case "/v3/pay/transactions/jsapi":
+			json.Unmarshal(body, &received)
+			rsp = `{"prepay_id":"wx26112221580621e9b071c00d9e093b0000"}`
+		case "/v3/pay/transactions/out-trade-no/1217752501201407033233368018?mchid=1230000109":
+			rsp = `{"mchid":"1230000109","out_trade_no":"1217752501201407033233368018","trade_state":"NOTPAY","amount":{"total":100,"currency":"CNY"}}`
+		case "/v3/pay/transactions/out-trade-no/1217752501201407033233368018/close":
+			env.sign(t, w.Header(), "")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"code":"RESOURCE_NOT_EXISTS","message":"not found"}`)
+			return
+		}
+		env.sign(t, w.Header(), rsp)
+		fmt.Fprint(w, rsp)
+	}))
+	defer srv.Close()
+
+	c := env.client(srv)
+	opts := TransactionOptions{
+		Description: "Image形象店-深圳腾大-QQ公仔",
+		OutTradeNo:  "1217752501201407033233368018",
+		TimeExpire:  time.Now().Add(time.Hour),
+		Amount:      OrderAmount{Total: 100, Currency: "CNY"},
+		SceneInfo:   &V3SceneInfo{PayerClientIP: "14.23.150.211"},
+	}
+	if _, err := c.PayJSAPI(opts); err == nil {
+		t.Error("expected failure without payer")
+	}
+
+	opts.Payer = &Payer{OpenID: "oUpF8uMuAJO_M2pxb1Q9zNjWeS6o"}
+	rsp, err := c.PayJSAPI(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rsp.PrepayID != "wx26112221580621e9b071c00d9e093b0000" {
+		t.Errorf("returned: %s", rsp.PrepayID)
+	}
+	if received["appid"] != "wxd678efh567hg6787" || received["mchid"] != "1230000109" {
+		t.Errorf("returned: %v", received)
+	}
+	if received["time_expire"] != opts.TimeExpire.Format(time.RFC3339) {
+		t.Errorf("returned: %v", received["time_expire"])
+	}
+	if received["amount"].(map[string]interface{})["total"] != float64(100) {
+		t.Errorf("returned: %v", received["amount"])
+	}
+
+	trans, err := c.QueryTransactionByOutTradeNo("1217752501201407033233368018")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trans.TradeState != TradeStateNotPay || trans.Amount.Total != 100 {
+		t.Errorf("returned: %#v", trans)
+	}
+
+	if err = c.CloseTransaction("1217752501201407033233368018"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestToJSAPIPayment(t *testing.T) {
+	env := newV3TestEnv(t)
+	c := NewV3Client(V3Config{AppID: "wxd678efh567hg6787", PrivateKey: env.merchantKey})
+
+	payment, err := c.ToJSAPIPayment("wx201410272009395522657a690389285100")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if payment.Package != "prepay_id=wx201410272009395522657a690389285100" || payment.SignType != "RSA" {
+		t.Errorf("returned: %#v", payment)
+	}
+
+	message := fmt.Sprintf("%s\n%s\n%s\n%s\n", payment.AppID, payment.TimeStamp, payment.NonceStr, payment.Package)
+	cert := &x509.Certificate{PublicKey: &env.merchantKey.PublicKey}
+	if err = verifySHA256WithRSA(cert, []byte(message), payment.PaySign); err != nil {
+		t.Error(err)
+	}
+}
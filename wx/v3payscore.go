@@ -0,0 +1,414 @@
+package wx
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+)
+
+// constants for event type of Pay Score notifications.
+const (
+	EventPayScoreUserConfirm      = "PAYSCORE.USER_CONFIRM"       // 用户确认订单
+	EventPayScoreUserPaid         = "PAYSCORE.USER_PAID"          // 用户支付成功
+	EventPayScoreUserOpenService  = "PAYSCORE.USER_OPEN_SERVICE"  // 用户授权
+	EventPayScoreUserCloseService = "PAYSCORE.USER_CLOSE_SERVICE" // 用户解除授权
+)
+
+// ServiceOrderState is the state of Pay Score service order.
+type ServiceOrderState string
+
+// constants for state of service order.
+const (
+	ServiceOrderCreated ServiceOrderState = "CREATED" // 商户已创建服务订单
+	ServiceOrderDoing   ServiceOrderState = "DOING"   // 服务订单进行中
+	ServiceOrderDone    ServiceOrderState = "DONE"    // 服务订单完成
+	ServiceOrderRevoked ServiceOrderState = "REVOKED" // 商户取消服务订单
+	ServiceOrderExpired ServiceOrderState = "EXPIRED" // 服务订单已失效
+)
+
+// constants for name of risk fund.
+const (
+	RiskFundDeposit           = "DEPOSIT"             // 押金
+	RiskFundAdvance           = "ADVANCE"             // 预付款
+	RiskFundCashDeposit       = "CASH_DEPOSIT"        // 保证金
+	RiskFundEstimateOrderCost = "ESTIMATE_ORDER_COST" // 预估订单费用
+)
+
+// PostPayment is a fee paid after the service in fen.
+type PostPayment struct {
+	Name        string `json:"name,omitempty"`        // 付费项目名称
+	Amount      int    `json:"amount,omitempty"`      // 金额
+	Description string `json:"description,omitempty"` // 计费说明
+	Count       int    `json:"count,omitempty"`       // 付费数量
+}
+
+// PostDiscount is a discount of the service in fen.
+type PostDiscount struct {
+	Name        string `json:"name,omitempty"`        // 优惠名称
+	Description string `json:"description,omitempty"` // 优惠说明
+	Amount      int    `json:"amount,omitempty"`      // 优惠金额
+	Count       int    `json:"count,omitempty"`       // 优惠数量
+}
+
+// RiskFund is the maximum amount of the service in fen, see the RiskFund
+// constants for Name.
+type RiskFund struct {
+	Name        string `json:"name"`                  // 风险金名称
+	Amount      int    `json:"amount"`                // 风险金额
+	Description string `json:"description,omitempty"` // 风险说明
+}
+
+// TimeRange is the time of the service, formatted as 20060102150405.
+// StartTime is OnAccept if the service starts once the user confirms.
+type TimeRange struct {
+	StartTime       string `json:"start_time,omitempty"`        // 服务开始时间
+	StartTimeRemark string `json:"start_time_remark,omitempty"` // 服务开始时间备注
+	EndTime         string `json:"end_time,omitempty"`          // 预计服务结束时间
+	EndTimeRemark   string `json:"end_time_remark,omitempty"`   // 预计服务结束时间备注
+}
+
+// ServiceLocation is where the service starts and ends.
+type ServiceLocation struct {
+	StartLocation string `json:"start_location,omitempty"` // 服务开始地点
+	EndLocation   string `json:"end_location,omitempty"`   // 预计服务结束位置
+}
+
+// ServiceOrderOptions contains fields of Pay Score service order.
+type ServiceOrderOptions struct {
+	OutOrderNo          string           `json:"out_order_no"`             // 商户服务订单号
+	ServiceIntroduction string           `json:"service_introduction"`     // 服务信息
+	PostPayments        []PostPayment    `json:"post_payments,omitempty"`  // 后付费项目
+	PostDiscounts       []PostDiscount   `json:"post_discounts,omitempty"` // 后付费商户优惠
+	TimeRange           TimeRange        `json:"time_range"`               // 服务时间段
+	Location            *ServiceLocation `json:"location,omitempty"`       // 服务位置
+	RiskFund            RiskFund         `json:"risk_fund"`                // 订单风险金
+	Attach              string           `json:"attach,omitempty"`         // 商户数据包
+	OpenID              string           `json:"openid,omitempty"`         // 用户标识
+	NeedUserConfirm     bool             `json:"need_user_confirm"`        // 是否需要用户确认
+}
+
+// CreateServiceOrder creates a Pay Score service order, which the user
+// confirms with Package if NeedUserConfirm.
+func (c *V3Client) CreateServiceOrder(opts ServiceOrderOptions) (*ServiceOrder, error) {
+	if opts.OutOrderNo == "" || opts.ServiceIntroduction == "" {
+		return nil, errors.New("out_order_no and service_introduction required")
+	}
+	if !opts.NeedUserConfirm && opts.OpenID == "" {
+		return nil, errors.New("openid required without user confirmation")
+	}
+
+	req := createServiceOrderReq{
+		AppID:               c.config.AppID,
+		ServiceID:           c.config.ServiceID,
+		NotifyURL:           c.config.NotifyURL,
+		ServiceOrderOptions: opts,
+	}
+
+	rsp := &ServiceOrder{}
+	if err := c.doRequest(http.MethodPost, "/v3/payscore/serviceorder", req, rsp); err != nil {
+		return nil, err
+	}
+	return rsp, nil
+}
+
+// QueryServiceOrder queries the service order by out_order_no.
+func (c *V3Client) QueryServiceOrder(outOrderNo string) (*ServiceOrder, error) {
+	return c.queryServiceOrder("out_order_no", outOrderNo)
+}
+
+// QueryServiceOrderByQueryID queries the service order by query_id, which is
+// returned by notifications.
+func (c *V3Client) QueryServiceOrderByQueryID(queryID string) (*ServiceOrder, error) {
+	return c.queryServiceOrder("query_id", queryID)
+}
+
+func (c *V3Client) queryServiceOrder(key, value string) (*ServiceOrder, error) {
+	query := url.Values{}
+	query.Set(key, value)
+	query.Set("service_id", c.config.ServiceID)
+	query.Set("appid", c.config.AppID)
+
+	rsp := &ServiceOrder{}
+	if err := c.doRequest(http.MethodGet, "/v3/payscore/serviceorder?"+query.Encode(), nil, rsp); err != nil {
+		return nil, err
+	}
+	return rsp, nil
+}
+
+// CancelServiceOrder cancels the service order before it is completed.
+func (c *V3Client) CancelServiceOrder(outOrderNo, reason string) (*ServiceOrder, error) {
+	req := serviceOrderActionReq{
+		AppID:     c.config.AppID,
+		ServiceID: c.config.ServiceID,
+		Reason:    reason,
+	}
+	return c.serviceOrderAction(outOrderNo, "cancel", req)
+}
+
+// ModifyServiceOrderOptions contains fields of modifying the amount of
+// service order, TotalAmount is computed from PostPayments and PostDiscounts.
+type ModifyServiceOrderOptions struct {
+	PostPayments  []PostPayment  `json:"post_payments"`            // 后付费项目
+	PostDiscounts []PostDiscount `json:"post_discounts,omitempty"` // 后付费商户优惠
+	Reason        string         `json:"reason"`                   // 修改原因
+}
+
+// ModifyServiceOrder modifies the amount of the completed but unpaid service
+// order.
+func (c *V3Client) ModifyServiceOrder(outOrderNo string, opts ModifyServiceOrderOptions) (*ServiceOrder, error) {
+	req := modifyServiceOrderReq{
+		AppID:                     c.config.AppID,
+		ServiceID:                 c.config.ServiceID,
+		TotalAmount:               totalAmount(opts.PostPayments, opts.PostDiscounts),
+		ModifyServiceOrderOptions: opts,
+	}
+	return c.serviceOrderAction(outOrderNo, "modify", req)
+}
+
+// CompleteServiceOrderOptions contains fields of completing service order,
+// TotalAmount is computed from PostPayments and PostDiscounts.
+type CompleteServiceOrderOptions struct {
+	PostPayments  []PostPayment    `json:"post_payments"`            // 后付费项目
+	PostDiscounts []PostDiscount   `json:"post_discounts,omitempty"` // 后付费商户优惠
+	TimeRange     *TimeRange       `json:"time_range,omitempty"`     // 服务时间段
+	Location      *ServiceLocation `json:"location,omitempty"`       // 服务位置
+	ProfitSharing bool             `json:"profit_sharing,omitempty"` // 是否指定分账
+	GoodsTag      string           `json:"goods_tag,omitempty"`      // 订单优惠标记
+}
+
+// CompleteServiceOrder completes the service order, charging the user the
+// total amount, which must not exceed the risk fund.
+func (c *V3Client) CompleteServiceOrder(outOrderNo string, opts CompleteServiceOrderOptions) (*ServiceOrder, error) {
+	req := completeServiceOrderReq{
+		AppID:                       c.config.AppID,
+		ServiceID:                   c.config.ServiceID,
+		TotalAmount:                 totalAmount(opts.PostPayments, opts.PostDiscounts),
+		CompleteServiceOrderOptions: opts,
+	}
+	return c.serviceOrderAction(outOrderNo, "complete", req)
+}
+
+// PayServiceOrder charges the user again for the completed but unpaid service
+// order.
+func (c *V3Client) PayServiceOrder(outOrderNo string) (*ServiceOrder, error) {
+	req := serviceOrderActionReq{
+		AppID:     c.config.AppID,
+		ServiceID: c.config.ServiceID,
+	}
+	return c.serviceOrderAction(outOrderNo, "pay", req)
+}
+
+// SyncServiceOrder syncs the service order paid by the user elsewhere at
+// paidTime, formatted as 20060102150405.
+func (c *V3Client) SyncServiceOrder(outOrderNo, paidTime string) (*ServiceOrder, error) {
+	req := syncServiceOrderReq{
+		AppID:     c.config.AppID,
+		ServiceID: c.config.ServiceID,
+		Type:      "Order_Paid",
+	}
+	req.Detail.PaidTime = paidTime
+	return c.serviceOrderAction(outOrderNo, "sync", req)
+}
+
+func (c *V3Client) serviceOrderAction(outOrderNo, action string, req interface{}) (*ServiceOrder, error) {
+	uri := "/v3/payscore/serviceorder/" + url.PathEscape(outOrderNo) + "/" + action
+	rsp := &ServiceOrder{}
+	if err := c.doRequest(http.MethodPost, uri, req, rsp); err != nil {
+		return nil, err
+	}
+	return rsp, nil
+}
+
+// totalAmount returns the amount of payments less discounts.
+func totalAmount(payments []PostPayment, discounts []PostDiscount) int {
+	total := 0
+	for _, p := range payments {
+		total += p.Amount
+	}
+	for _, d := range discounts {
+		total -= d.Amount
+	}
+	return total
+}
+
+// ApplyPermissions applies for the user authorization of Pay Score,
+// authorizationCode identifies the authorization on the merchant side.
+func (c *V3Client) ApplyPermissions(authorizationCode string) (*ApplyPermissionsRsp, error) {
+	req := map[string]string{
+		"service_id":         c.config.ServiceID,
+		"appid":              c.config.AppID,
+		"authorization_code": authorizationCode,
+		"notify_url":         c.config.NotifyURL,
+	}
+
+	rsp := &ApplyPermissionsRsp{}
+	if err := c.doRequest(http.MethodPost, "/v3/payscore/permissions", req, rsp); err != nil {
+		return nil, err
+	}
+	return rsp, nil
+}
+
+// QueryPermissions queries the user authorization by authorization_code.
+func (c *V3Client) QueryPermissions(authorizationCode string) (*PayScorePermissions, error) {
+	uri := "/v3/payscore/permissions/authorization-code/" + url.PathEscape(authorizationCode) +
+		"?service_id=" + url.QueryEscape(c.config.ServiceID)
+	rsp := &PayScorePermissions{}
+	if err := c.doRequest(http.MethodGet, uri, nil, rsp); err != nil {
+		return nil, err
+	}
+	return rsp, nil
+}
+
+// QueryPermissionsByOpenID queries the user authorization by openid.
+func (c *V3Client) QueryPermissionsByOpenID(openID string) (*PayScorePermissions, error) {
+	query := url.Values{}
+	query.Set("appid", c.config.AppID)
+	query.Set("service_id", c.config.ServiceID)
+
+	uri := "/v3/payscore/permissions/openid/" + url.PathEscape(openID) + "?" + query.Encode()
+	rsp := &PayScorePermissions{}
+	if err := c.doRequest(http.MethodGet, uri, nil, rsp); err != nil {
+		return nil, err
+	}
+	return rsp, nil
+}
+
+// TerminatePermissions terminates the user authorization by
+// authorization_code.
+func (c *V3Client) TerminatePermissions(authorizationCode, reason string) error {
+	uri := "/v3/payscore/permissions/authorization-code/" + url.PathEscape(authorizationCode) + "/terminate"
+	req := map[string]string{
+		"service_id": c.config.ServiceID,
+		"reason":     reason,
+	}
+	return c.doRequest(http.MethodPost, uri, req, nil)
+}
+
+// TerminatePermissionsByOpenID terminates the user authorization by openid.
+func (c *V3Client) TerminatePermissionsByOpenID(openID, reason string) error {
+	uri := "/v3/payscore/permissions/openid/" + url.PathEscape(openID) + "/terminate"
+	req := map[string]string{
+		"appid":      c.config.AppID,
+		"service_id": c.config.ServiceID,
+		"reason":     reason,
+	}
+	return c.doRequest(http.MethodPost, uri, req, nil)
+}
+
+// ServiceOrder decodes Plaintext of PAYSCORE.USER_CONFIRM and
+// PAYSCORE.USER_PAID events.
+func (n *V3Notification) ServiceOrder() (*ServiceOrder, error) {
+	result := &ServiceOrder{}
+	if err := n.Decode(result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// PayScorePermissions decodes Plaintext of PAYSCORE.USER_OPEN_SERVICE and
+// PAYSCORE.USER_CLOSE_SERVICE events.
+func (n *V3Notification) PayScorePermissions() (*PayScorePermissions, error) {
+	result := &PayScorePermissions{}
+	if err := n.Decode(result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+type createServiceOrderReq struct {
+	AppID     string `json:"appid"`      // 应用ID
+	ServiceID string `json:"service_id"` // 服务ID
+	NotifyURL string `json:"notify_url"` // 商户回调地址
+	ServiceOrderOptions
+}
+
+type serviceOrderActionReq struct {
+	AppID     string `json:"appid"`            // 应用ID
+	ServiceID string `json:"service_id"`       // 服务ID
+	Reason    string `json:"reason,omitempty"` // 取消原因
+}
+
+type modifyServiceOrderReq struct {
+	AppID       string `json:"appid"`        // 应用ID
+	ServiceID   string `json:"service_id"`   // 服务ID
+	TotalAmount int    `json:"total_amount"` // 总金额
+	ModifyServiceOrderOptions
+}
+
+type completeServiceOrderReq struct {
+	AppID       string `json:"appid"`        // 应用ID
+	ServiceID   string `json:"service_id"`   // 服务ID
+	TotalAmount int    `json:"total_amount"` // 总金额
+	CompleteServiceOrderOptions
+}
+
+type syncServiceOrderReq struct {
+	AppID     string `json:"appid"`      // 应用ID
+	ServiceID string `json:"service_id"` // 服务ID
+	Type      string `json:"type"`       // 场景类型
+	Detail    struct {
+		PaidTime string `json:"paid_time"` // 收款成功时间
+	} `json:"detail"` // 内容信息详情
+}
+
+// ServiceOrder is the Pay Score service order.
+type ServiceOrder struct {
+	AppID               string            `json:"appid"`                    // 应用ID
+	MchID               string            `json:"mchid"`                    // 商户号
+	OutOrderNo          string            `json:"out_order_no"`             // 商户服务订单号
+	ServiceID           string            `json:"service_id"`               // 服务ID
+	ServiceIntroduction string            `json:"service_introduction"`     // 服务信息
+	State               ServiceOrderState `json:"state"`                    // 服务订单状态
+	StateDescription    string            `json:"state_description"`        // 订单状态说明
+	TotalAmount         int               `json:"total_amount"`             // 商户收款总金额
+	PostPayments        []PostPayment     `json:"post_payments,omitempty"`  // 后付费项目
+	PostDiscounts       []PostDiscount    `json:"post_discounts,omitempty"` // 后付费商户优惠
+	RiskFund            *RiskFund         `json:"risk_fund,omitempty"`      // 订单风险金
+	TimeRange           *TimeRange        `json:"time_range,omitempty"`     // 服务时间段
+	Location            *ServiceLocation  `json:"location,omitempty"`       // 服务位置
+	Attach              string            `json:"attach"`                   // 商户数据包
+	NotifyURL           string            `json:"notify_url"`               // 商户回调地址
+	OrderID             string            `json:"order_id"`                 // 微信支付服务订单号
+	Package             string            `json:"package"`                  // 跳转微信侧小程序订单数据
+	NeedCollection      bool              `json:"need_collection"`          // 是否需要收款
+	Collection          *Collection       `json:"collection,omitempty"`     // 收款信息
+	OpenID              string            `json:"openid"`                   // 用户标识
+}
+
+// Collection is the payment of service order in fen.
+type Collection struct {
+	State        string             `json:"state"`             // 收款状态，USER_PAYING或USER_PAID
+	TotalAmount  int                `json:"total_amount"`      // 总收款金额
+	PayingAmount int                `json:"paying_amount"`     // 待收金额
+	PaidAmount   int                `json:"paid_amount"`       // 已收金额
+	Details      []CollectionDetail `json:"details,omitempty"` // 收款明细列表
+}
+
+// CollectionDetail is a payment of service order in fen.
+type CollectionDetail struct {
+	Seq             int               `json:"seq"`                        // 收款序号
+	Amount          int               `json:"amount"`                     // 单笔收款金额
+	PaidType        string            `json:"paid_type"`                  // 收款成功渠道，NEWTON或MCH
+	PaidTime        string            `json:"paid_time"`                  // 收款成功时间
+	TransactionID   string            `json:"transaction_id"`             // 微信支付交易单号
+	PromotionDetail []PromotionDetail `json:"promotion_detail,omitempty"` // 优惠功能
+}
+
+// ApplyPermissionsRsp is the response returned by /v3/payscore/permissions.
+type ApplyPermissionsRsp struct {
+	ApplyPermissionsToken string `json:"apply_permissions_token"` // 预授权token
+}
+
+// PayScorePermissions is the user authorization of Pay Score.
+type PayScorePermissions struct {
+	AppID                    string `json:"appid"`                      // 应用ID
+	MchID                    string `json:"mchid"`                      // 商户号
+	ServiceID                string `json:"service_id"`                 // 服务ID
+	OpenID                   string `json:"openid"`                     // 用户标识
+	AuthorizationCode        string `json:"authorization_code"`         // 授权协议号
+	AuthorizationState       string `json:"authorization_state"`        // 授权状态
+	UserServiceStatus        string `json:"user_service_status"`        // 授权状态（通知）
+	OpenOrCloseTime          string `json:"openorclose_time"`           // 授权或解除授权时间（通知）
+	CancelAuthorizationTime  string `json:"cancel_authorization_time"`  // 最近一次解除授权时间
+	AuthorizationSuccessTime string `json:"authorization_success_time"` // 最近一次授权成功时间
+}
@@ -0,0 +1,65 @@
+package wx
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestServiceOrder(t *testing.T) {
+	env := newV3TestEnv(t)
+	var created createServiceOrderReq
+	var completed completeServiceOrderReq
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		var rsp string
+		switch r.URL.Path {
+		case "/v3/payscore/serviceorder":
+			json.Unmarshal(body, &created)
+			rsp = `{"out_order_no":"1234323JKHDFE1243252","service_id":"500001","state":"CREATED","order_id":"15646546545165651651",` +
+				`"risk_fund":{"name":"DEPOSIT","amount":10000},"package":"DJIOSQPYWDxsjdldeskdfmwi=="}`
+		case "/v3/payscore/serviceorder/1234323JKHDFE1243252/complete":
+			json.Unmarshal(body, &completed)
+			rsp = `{"out_order_no":"1234323JKHDFE1243252","state":"DOING","state_description":"MCH_COMPLETE","total_amount":3900}`
+		}
+		env.sign(t, w.Header(), rsp)
+		fmt.Fprint(w, rsp)
+	}))
+	defer srv.Close()
+
+	c := env.client(srv)
+	c.config.ServiceID = "500001"
+	order, err := c.CreateServiceOrder(ServiceOrderOptions{
+		OutOrderNo:          "1234323JKHDFE1243252",
+		ServiceIntroduction: "某某酒店",
+		TimeRange:           TimeRange{StartTime: "OnAccept"},
+		RiskFund:            RiskFund{Name: RiskFundDeposit, Amount: 10000},
+		NeedUserConfirm:     true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.State != ServiceOrderCreated || order.RiskFund.Amount != 10000 || order.Package == "" {
+		t.Errorf("returned: %#v", order)
+	}
+	if created.ServiceID != "500001" || created.AppID != "wxd678efh567hg6787" || !created.NeedUserConfirm {
+		t.Errorf("returned: %#v", created)
+	}
+
+	order, err = c.CompleteServiceOrder("1234323JKHDFE1243252", CompleteServiceOrderOptions{
+		PostPayments:  []PostPayment{{Name: "就餐费用", Amount: 4000, Count: 1}},
+		PostDiscounts: []PostDiscount{{Name: "满20减1元", Amount: 100, Count: 1}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if completed.TotalAmount != 3900 {
+		t.Errorf("returned: %d, expected: 3900", completed.TotalAmount)
+	}
+	if order.State != ServiceOrderDoing || order.StateDescription != "MCH_COMPLETE" {
+		t.Errorf("returned: %#v", order)
+	}
+}
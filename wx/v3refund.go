@@ -0,0 +1,176 @@
+package wx
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+)
+
+// RefundStatus is the status of refund in API v3.
+type RefundStatus string
+
+// constants for refund status in API v3.
+const (
+	RefundStatusSuccess    RefundStatus = "SUCCESS"    // 退款成功
+	RefundStatusClosed     RefundStatus = "CLOSED"     // 退款关闭
+	RefundStatusProcessing RefundStatus = "PROCESSING" // 退款处理中
+	RefundStatusAbnormal   RefundStatus = "ABNORMAL"   // 退款异常
+)
+
+// constants for funds account of refund and fund flow bill.
+const (
+	FundsAccountAvailable   = "AVAILABLE"   // 可用余额
+	FundsAccountUnsettled   = "UNSETTLED"   // 未结算资金
+	FundsAccountUnavailable = "UNAVAILABLE" // 不可用余额
+	FundsAccountOperation   = "OPERATION"   // 运营户
+	FundsAccountBasic       = "BASIC"       // 基本账户（含可用余额和不可用余额）
+	FundsAccountFees        = "FEES"        // 手续费账户
+)
+
+// constants for channel of refund.
+const (
+	RefundChannelOriginal      = "ORIGINAL"       // 原路退款
+	RefundChannelBalance       = "BALANCE"        // 退回到余额
+	RefundChannelOtherBalance  = "OTHER_BALANCE"  // 原账户异常退到其他余额账户
+	RefundChannelOtherBankCard = "OTHER_BANKCARD" // 原银行卡异常退到其他银行卡
+)
+
+// constants for type of abnormal refund.
+const (
+	AbnormalRefundUserBankCard     = "USER_BANK_CARD"     // 退款到用户银行卡
+	AbnormalRefundMerchantBankCard = "MERCHANT_BANK_CARD" // 退款至交易商户银行账户
+)
+
+// V3RefundOptions contains fields of refund in API v3, either TransactionID
+// or OutTradeNo is required.
+type V3RefundOptions struct {
+	TransactionID string              `json:"transaction_id,omitempty"` // 微信支付订单号
+	OutTradeNo    string              `json:"out_trade_no,omitempty"`   // 商户订单号
+	OutRefundNo   string              `json:"out_refund_no"`            // 商户退款单号
+	Reason        string              `json:"reason,omitempty"`         // 退款原因
+	FundsAccount  string              `json:"funds_account,omitempty"`  // 退款资金来源，仅支持AVAILABLE
+	Amount        RefundAmount        `json:"amount"`                   // 金额信息
+	GoodsDetail   []RefundGoodsDetail `json:"goods_detail,omitempty"`   // 退款商品
+}
+
+// RefundAmount is the amount of refund in fen.
+type RefundAmount struct {
+	Refund   int          `json:"refund"`             // 退款金额
+	From     []RefundFrom `json:"from,omitempty"`     // 退款出资账户及金额
+	Total    int          `json:"total"`              // 原订单金额
+	Currency string       `json:"currency,omitempty"` // 退款币种，CNY by default
+}
+
+// RefundFrom is the account and amount of refund from.
+type RefundFrom struct {
+	Account string `json:"account"` // 出资账户类型，AVAILABLE或UNAVAILABLE
+	Amount  int    `json:"amount"`  // 出资金额
+}
+
+// RefundGoodsDetail is a goods refunded.
+type RefundGoodsDetail struct {
+	MerchantGoodsID  string `json:"merchant_goods_id"`            // 商户侧商品编码
+	WechatpayGoodsID string `json:"wechatpay_goods_id,omitempty"` // 微信支付商品编码
+	GoodsName        string `json:"goods_name,omitempty"`         // 商品名称
+	UnitPrice        int    `json:"unit_price"`                   // 商品单价
+	RefundAmount     int    `json:"refund_amount"`                // 商品退款金额
+	RefundQuantity   int    `json:"refund_quantity"`              // 商品退货数量
+}
+
+// CreateRefund refunds the order, the result is notified to NotifyURL.
+func (c *V3Client) CreateRefund(opts V3RefundOptions) (*V3Refund, error) {
+	if opts.TransactionID == "" && opts.OutTradeNo == "" {
+		return nil, errors.New("transaction_id or out_trade_no required")
+	}
+	if opts.OutRefundNo == "" {
+		return nil, errors.New("out_refund_no required")
+	}
+	if opts.Amount.Refund <= 0 || opts.Amount.Refund > opts.Amount.Total {
+		return nil, errors.New("refund amount must be positive and no more than total")
+	}
+
+	req := refundReq{
+		NotifyURL:       c.config.NotifyURL,
+		V3RefundOptions: opts,
+	}
+
+	rsp := &V3Refund{}
+	if err := c.doRequest(http.MethodPost, "/v3/refund/domestic/refunds", req, rsp); err != nil {
+		return nil, err
+	}
+	return rsp, nil
+}
+
+// QueryRefund queries the refund of outRefundNo.
+func (c *V3Client) QueryRefund(outRefundNo string) (*V3Refund, error) {
+	rsp := &V3Refund{}
+	if err := c.doRequest(http.MethodGet, "/v3/refund/domestic/refunds/"+url.PathEscape(outRefundNo), nil, rsp); err != nil {
+		return nil, err
+	}
+	return rsp, nil
+}
+
+// AbnormalRefundOptions contains fields of abnormal refund.
+// BankType, BankAccount and RealName are required by USER_BANK_CARD.
+type AbnormalRefundOptions struct {
+	OutRefundNo string `json:"out_refund_no"`                              // 商户退款单号
+	Type        string `json:"type"`                                       // 异常退款处理方式
+	BankType    string `json:"bank_type,omitempty"`                        // 开户银行
+	BankAccount string `json:"bank_account,omitempty" wechatpay:"encrypt"` // 收款银行卡号
+	RealName    string `json:"real_name,omitempty" wechatpay:"encrypt"`    // 收款用户姓名
+}
+
+// ApplyAbnormalRefund retries the refund of refundID in ABNORMAL status.
+func (c *V3Client) ApplyAbnormalRefund(refundID string, opts AbnormalRefundOptions) (*V3Refund, error) {
+	uri := "/v3/refund/domestic/refunds/" + url.PathEscape(refundID) + "/apply-abnormal-refund"
+	rsp := &V3Refund{}
+	if err := c.doRequest(http.MethodPost, uri, opts, rsp); err != nil {
+		return nil, err
+	}
+	return rsp, nil
+}
+
+type refundReq struct {
+	NotifyURL string `json:"notify_url,omitempty"` // 退款结果回调url
+	V3RefundOptions
+}
+
+// V3Refund is the refund of API v3.
+type V3Refund struct {
+	RefundID            string                  `json:"refund_id"`                  // 微信支付退款单号
+	OutRefundNo         string                  `json:"out_refund_no"`              // 商户退款单号
+	TransactionID       string                  `json:"transaction_id"`             // 微信支付订单号
+	OutTradeNo          string                  `json:"out_trade_no"`               // 商户订单号
+	Channel             string                  `json:"channel"`                    // 退款渠道
+	UserReceivedAccount string                  `json:"user_received_account"`      // 退款入账账户
+	SuccessTime         string                  `json:"success_time"`               // 退款成功时间
+	CreateTime          string                  `json:"create_time"`                // 退款创建时间
+	Status              RefundStatus            `json:"status"`                     // 退款状态
+	FundsAccount        string                  `json:"funds_account"`              // 资金账户
+	Amount              V3RefundAmount          `json:"amount"`                     // 金额信息
+	PromotionDetail     []RefundPromotionDetail `json:"promotion_detail,omitempty"` // 优惠退款信息
+}
+
+// V3RefundAmount is the amount of refund returned in fen.
+type V3RefundAmount struct {
+	Total            int          `json:"total"`             // 订单金额
+	Refund           int          `json:"refund"`            // 退款金额
+	From             []RefundFrom `json:"from,omitempty"`    // 退款出资的账户类型及金额信息
+	PayerTotal       int          `json:"payer_total"`       // 用户支付金额
+	PayerRefund      int          `json:"payer_refund"`      // 用户退款金额
+	SettlementRefund int          `json:"settlement_refund"` // 应结退款金额
+	SettlementTotal  int          `json:"settlement_total"`  // 应结订单金额
+	DiscountRefund   int          `json:"discount_refund"`   // 优惠退款金额
+	Currency         string       `json:"currency"`          // 退款币种
+	RefundFee        int          `json:"refund_fee"`        // 手续费退款金额
+}
+
+// RefundPromotionDetail is a promotion refunded.
+type RefundPromotionDetail struct {
+	PromotionID  string              `json:"promotion_id"`           // 券ID
+	Scope        string              `json:"scope"`                  // 优惠范围
+	Type         string              `json:"type"`                   // 优惠类型
+	Amount       int                 `json:"amount"`                 // 优惠券面额
+	RefundAmount int                 `json:"refund_amount"`          // 优惠退款金额
+	GoodsDetail  []RefundGoodsDetail `json:"goods_detail,omitempty"` // 商品列表
+}
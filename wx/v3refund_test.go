@@ -0,0 +1,57 @@
+package wx
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCreateRefund(t *testing.T) {
+	env := newV3TestEnv(t)
+	var received refundReq
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(body, &received)
+
+		rsp := `{"refund_id":"50000000382019052709732678859","out_refund_no":"1217752501201407033233368018",` +
+			`"channel":"ORIGINAL","status":"PROCESSING","funds_account":"AVAILABLE",` +
+			`"amount":{"total":100,"refund":50,"from":[{"account":"AVAILABLE","amount":50}],"payer_refund":50,"currency":"CNY"}}`
+		env.sign(t, w.Header(), rsp)
+		fmt.Fprint(w, rsp)
+	}))
+	defer srv.Close()
+
+	c := env.client(srv)
+	c.config.NotifyURL = "https://weixin.qq.com/refund"
+	opts := V3RefundOptions{
+		OutTradeNo:   "1217752501201407033233368018",
+		OutRefundNo:  "1217752501201407033233368018",
+		FundsAccount: FundsAccountAvailable,
+		Amount:       RefundAmount{Refund: 50, Total: 100, Currency: "CNY"},
+		GoodsDetail: []RefundGoodsDetail{{
+			MerchantGoodsID: "1217752501201407033233368018",
+			UnitPrice:       100,
+			RefundAmount:    50,
+			RefundQuantity:  1,
+		}},
+	}
+
+	rsp, err := c.CreateRefund(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rsp.Status != RefundStatusProcessing || rsp.Amount.From[0].Amount != 50 {
+		t.Errorf("returned: %#v", rsp)
+	}
+	if received.NotifyURL != "https://weixin.qq.com/refund" || len(received.GoodsDetail) != 1 {
+		t.Errorf("returned: %#v", received)
+	}
+
+	opts.Amount.Refund = 200
+	if _, err = c.CreateRefund(opts); err == nil {
+		t.Error("expected failure with refund more than total")
+	}
+}
@@ -0,0 +1,178 @@
+package wx
+
+import (
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+)
+
+// sensitiveTag is the struct tag marking string fields encrypted with
+// RSA-OAEP: `wechatpay:"encrypt"`. They are encrypted with the platform
+// certificate in requests and decrypted with the merchant private key in
+// responses.
+const sensitiveTag = "wechatpay"
+
+// CertificateSource is implemented by Verifiers which provide the platform
+// certificate to encrypt sensitive fields.
+type CertificateSource interface {
+	LatestCertificate() (*x509.Certificate, error)
+}
+
+// LatestCertificate returns the certificate expiring last.
+func (v certificateVerifier) LatestCertificate() (*x509.Certificate, error) {
+	var latest *x509.Certificate
+	for _, cert := range v {
+		if latest == nil || cert.NotAfter.After(latest.NotAfter) {
+			latest = cert
+		}
+	}
+	if latest == nil {
+		return nil, errors.New("no platform certificate")
+	}
+	return latest, nil
+}
+
+// LatestCertificate returns the certificate expiring last, downloading the
+// certificates if none is cached.
+func (m *CertificateManager) LatestCertificate() (*x509.Certificate, error) {
+	m.mu.RLock()
+	certs := certificateVerifier(m.certs)
+	latest, err := certs.LatestCertificate()
+	m.mu.RUnlock()
+	if err == nil {
+		return latest, nil
+	}
+
+	if err = m.refresh(true); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return certificateVerifier(m.certs).LatestCertificate()
+}
+
+// encryptRequest returns a copy of req with sensitive fields encrypted, and
+// the Wechatpay-Serial header of the certificate used. req is returned as is
+// if it has no sensitive fields.
+func (c *V3Client) encryptRequest(req interface{}) (interface{}, http.Header, error) {
+	if req == nil {
+		return req, nil, nil
+	}
+
+	count := 0
+	walkSensitive(reflect.ValueOf(req), func(f reflect.Value) error {
+		count++
+		return nil
+	})
+	if count == 0 {
+		return req, nil, nil
+	}
+
+	source, ok := c.verifier.(CertificateSource)
+	if !ok {
+		return nil, nil, errors.New("no platform certificate to encrypt sensitive fields")
+	}
+	cert, err := source.LatestCertificate()
+	if err != nil {
+		return nil, nil, err
+	}
+	pub, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return nil, nil, errors.New("need a RSA public key of platform certificate")
+	}
+
+	// a deep copy, so that the fields of req are left unchanged
+	data, err := json.Marshal(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	cp := reflect.New(reflect.TypeOf(req))
+	if err = json.Unmarshal(data, cp.Interface()); err != nil {
+		return nil, nil, err
+	}
+
+	err = walkSensitive(cp, func(f reflect.Value) error {
+		cipher, err := encryptOAEP(pub, f.String())
+		if err != nil {
+			return err
+		}
+		f.SetString(cipher)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	header := http.Header{}
+	header.Set("Wechatpay-Serial", serialNumber(cert))
+	return cp.Interface(), header, nil
+}
+
+// decryptResponse decrypts sensitive fields of rsp with the merchant private
+// key.
+func (c *V3Client) decryptResponse(rsp interface{}) error {
+	return walkSensitive(reflect.ValueOf(rsp), func(f reflect.Value) error {
+		if !f.CanSet() {
+			return errors.New("response must be a pointer to decrypt sensitive fields")
+		}
+
+		data, err := base64.StdEncoding.DecodeString(f.String())
+		if err != nil {
+			return err
+		}
+		plain, err := rsa.DecryptOAEP(sha1.New(), nil, c.config.PrivateKey, data, nil)
+		if err != nil {
+			return err
+		}
+		f.SetString(string(plain))
+		return nil
+	})
+}
+
+// walkSensitive calls fn with every non-empty string field tagged as
+// sensitive in v, including those in nested structs, pointers and slices.
+func walkSensitive(v reflect.Value, fn func(f reflect.Value) error) error {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil
+		}
+		return walkSensitive(v.Elem(), fn)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := walkSensitive(v.Index(i), fn); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		typ := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			sf := typ.Field(i)
+			if sf.PkgPath != "" && !sf.Anonymous {
+				continue
+			}
+
+			f := v.Field(i)
+			if sf.Tag.Get(sensitiveTag) == "encrypt" && f.Kind() == reflect.String {
+				if f.String() == "" {
+					continue
+				}
+				if err := fn(f); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if err := walkSensitive(f, fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
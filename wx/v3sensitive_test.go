@@ -0,0 +1,74 @@
+package wx
+
+import (
+	"crypto/rsa"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSensitiveFields(t *testing.T) {
+	env := newV3TestEnv(t)
+	var received AbnormalRefundOptions
+	var serialNo string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(body, &received)
+		serialNo = r.Header.Get("Wechatpay-Serial")
+
+		name, err := encryptOAEP(&env.merchantKey.PublicKey, "张三")
+		if err != nil {
+			t.Fatal(err)
+		}
+		rsp := fmt.Sprintf(`{"refund_id":"50000000382019052709732678859","out_refund_no":"%s","user_name":"%s"}`, received.OutRefundNo, name)
+		env.sign(t, w.Header(), rsp)
+		fmt.Fprint(w, rsp)
+	}))
+	defer srv.Close()
+
+	c := env.client(srv)
+	opts := AbnormalRefundOptions{
+		OutRefundNo: "1217752501201407033233368018",
+		Type:        AbnormalRefundUserBankCard,
+		BankType:    "ICBC_DEBIT",
+		BankAccount: "6222020202020202020",
+		RealName:    "张三",
+	}
+	rsp := &struct {
+		RefundID string `json:"refund_id"`
+		UserName string `json:"user_name" wechatpay:"encrypt"`
+	}{}
+	uri := "/v3/refund/domestic/refunds/50000000382019052709732678859/apply-abnormal-refund"
+	if err := c.doRequest(http.MethodPost, uri, opts, rsp); err != nil {
+		t.Fatal(err)
+	}
+
+	if serialNo != serialNumber(env.platformCrt) {
+		t.Errorf("returned: %s, expected: %s", serialNo, serialNumber(env.platformCrt))
+	}
+	for field, expected := range map[string]string{received.BankAccount: "6222020202020202020", received.RealName: "张三"} {
+		data, _ := base64.StdEncoding.DecodeString(field)
+		plain, err := rsa.DecryptOAEP(sha1.New(), nil, env.platformKey, data, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(plain) != expected {
+			t.Errorf("returned: %s, expected: %s", plain, expected)
+		}
+	}
+	if received.BankType != "ICBC_DEBIT" {
+		t.Errorf("returned: %s", received.BankType)
+	}
+	if opts.RealName != "张三" {
+		t.Error("expected options to be left unchanged")
+	}
+
+	if rsp.UserName != "张三" {
+		t.Errorf("returned: %s, expected: 张三", rsp.UserName)
+	}
+}
@@ -0,0 +1,300 @@
+package wx
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// maxTransferDetails is the maximum number of details in a transfer batch.
+const maxTransferDetails = 1000
+
+// minNamedTransferAmount is the minimum amount in fen requiring user_name.
+const minNamedTransferAmount = 200000
+
+// TransferBatchStatus is the status of transfer batch.
+type TransferBatchStatus string
+
+// constants for status of transfer batch.
+const (
+	TransferBatchAccepted   TransferBatchStatus = "ACCEPTED"   // 已受理
+	TransferBatchProcessing TransferBatchStatus = "PROCESSING" // 转账中
+	TransferBatchFinished   TransferBatchStatus = "FINISHED"   // 已完成
+	TransferBatchClosed     TransferBatchStatus = "CLOSED"     // 已关闭
+)
+
+// TransferDetailStatus is the status of transfer detail.
+type TransferDetailStatus string
+
+// constants for status of transfer detail.
+const (
+	TransferDetailInit       TransferDetailStatus = "INIT"       // 初始态
+	TransferDetailWaitPay    TransferDetailStatus = "WAIT_PAY"   // 待确认
+	TransferDetailProcessing TransferDetailStatus = "PROCESSING" // 转账中
+	TransferDetailSuccess    TransferDetailStatus = "SUCCESS"    // 转账成功
+	TransferDetailFail       TransferDetailStatus = "FAIL"       // 转账失败
+)
+
+// TransferBatchOptions contains fields of transfer batch, TotalAmount and
+// TotalNum are computed from TransferDetailList.
+type TransferBatchOptions struct {
+	OutBatchNo         string           `json:"out_batch_no"`                // 商家批次单号
+	BatchName          string           `json:"batch_name"`                  // 批次名称
+	BatchRemark        string           `json:"batch_remark"`                // 批次备注
+	TransferDetailList []TransferDetail `json:"transfer_detail_list"`        // 转账明细列表，最多1000笔
+	TransferSceneID    string           `json:"transfer_scene_id,omitempty"` // 转账场景ID
+}
+
+// TransferDetail is a transfer to the user of OpenID in fen. UserName is
+// required for amounts of 2000 yuan or more.
+type TransferDetail struct {
+	OutDetailNo    string `json:"out_detail_no"`                           // 商家明细单号
+	TransferAmount int    `json:"transfer_amount"`                         // 转账金额
+	TransferRemark string `json:"transfer_remark"`                         // 转账备注
+	OpenID         string `json:"openid"`                                  // 收款用户openid
+	UserName       string `json:"user_name,omitempty" wechatpay:"encrypt"` // 收款用户姓名
+}
+
+// CreateTransferBatch transfers to the balance of users in a batch of up to
+// 1000 details, whose results are queried with QueryTransferBatch.
+func (c *V3Client) CreateTransferBatch(opts TransferBatchOptions) (*CreateTransferBatchRsp, error) {
+	if len(opts.TransferDetailList) == 0 || len(opts.TransferDetailList) > maxTransferDetails {
+		return nil, fmt.Errorf("invalid %d transfer details, 1 to %d required", len(opts.TransferDetailList), maxTransferDetails)
+	}
+
+	req := transferBatchReq{
+		AppID:                c.config.AppID,
+		TotalNum:             len(opts.TransferDetailList),
+		TransferBatchOptions: opts,
+	}
+	for _, detail := range opts.TransferDetailList {
+		if detail.TransferAmount <= 0 {
+			return nil, fmt.Errorf("invalid transfer amount %d of %s", detail.TransferAmount, detail.OutDetailNo)
+		}
+		if detail.TransferAmount >= minNamedTransferAmount && detail.UserName == "" {
+			return nil, fmt.Errorf("user_name of %s required for 2000 yuan or more", detail.OutDetailNo)
+		}
+		req.TotalAmount += detail.TransferAmount
+	}
+
+	rsp := &CreateTransferBatchRsp{}
+	if err := c.doRequest(http.MethodPost, "/v3/transfer/batches", req, rsp); err != nil {
+		return nil, err
+	}
+	return rsp, nil
+}
+
+// QueryTransferBatchOptions contains fields of querying transfer batch.
+// Details are returned only if NeedQueryDetail, Limit is 20 by default and
+// up to 100.
+type QueryTransferBatchOptions struct {
+	NeedQueryDetail bool   // 是否查询转账明细单
+	Offset          int    // 请求资源起始位置
+	Limit           int    // 最大资源条数
+	DetailStatus    string // 明细状态，ALL、SUCCESS或FAIL
+}
+
+func (opts QueryTransferBatchOptions) query() string {
+	query := url.Values{}
+	query.Set("need_query_detail", strconv.FormatBool(opts.NeedQueryDetail))
+	if opts.Offset > 0 {
+		query.Set("offset", strconv.Itoa(opts.Offset))
+	}
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.DetailStatus != "" {
+		query.Set("detail_status", opts.DetailStatus)
+	}
+	return query.Encode()
+}
+
+// QueryTransferBatch queries the transfer batch by batch_id, it should be
+// polled until the batch is FINISHED or CLOSED.
+func (c *V3Client) QueryTransferBatch(batchID string, opts QueryTransferBatchOptions) (*QueryTransferBatchRsp, error) {
+	uri := "/v3/transfer/batches/batch-id/" + url.PathEscape(batchID) + "?" + opts.query()
+	rsp := &QueryTransferBatchRsp{}
+	if err := c.doRequest(http.MethodGet, uri, nil, rsp); err != nil {
+		return nil, err
+	}
+	return rsp, nil
+}
+
+// QueryTransferBatchByOutBatchNo queries the transfer batch by out_batch_no.
+func (c *V3Client) QueryTransferBatchByOutBatchNo(outBatchNo string, opts QueryTransferBatchOptions) (*QueryTransferBatchRsp, error) {
+	uri := "/v3/transfer/batches/out-batch-no/" + url.PathEscape(outBatchNo) + "?" + opts.query()
+	rsp := &QueryTransferBatchRsp{}
+	if err := c.doRequest(http.MethodGet, uri, nil, rsp); err != nil {
+		return nil, err
+	}
+	return rsp, nil
+}
+
+// QueryTransferDetail queries the transfer detail by batch_id and detail_id.
+func (c *V3Client) QueryTransferDetail(batchID, detailID string) (*TransferDetailRsp, error) {
+	uri := "/v3/transfer/batches/batch-id/" + url.PathEscape(batchID) + "/details/detail-id/" + url.PathEscape(detailID)
+	rsp := &TransferDetailRsp{}
+	if err := c.doRequest(http.MethodGet, uri, nil, rsp); err != nil {
+		return nil, err
+	}
+	return rsp, nil
+}
+
+// QueryTransferDetailByOutDetailNo queries the transfer detail by
+// out_batch_no and out_detail_no.
+func (c *V3Client) QueryTransferDetailByOutDetailNo(outBatchNo, outDetailNo string) (*TransferDetailRsp, error) {
+	uri := "/v3/transfer/batches/out-batch-no/" + url.PathEscape(outBatchNo) + "/details/out-detail-no/" + url.PathEscape(outDetailNo)
+	rsp := &TransferDetailRsp{}
+	if err := c.doRequest(http.MethodGet, uri, nil, rsp); err != nil {
+		return nil, err
+	}
+	return rsp, nil
+}
+
+// ApplyBatchReceipt applies for the electronic receipt of the finished
+// transfer batch.
+func (c *V3Client) ApplyBatchReceipt(outBatchNo string) (*TransferReceipt, error) {
+	rsp := &TransferReceipt{}
+	req := map[string]string{"out_batch_no": outBatchNo}
+	if err := c.doRequest(http.MethodPost, "/v3/transfer/bill-receipt", req, rsp); err != nil {
+		return nil, err
+	}
+	return rsp, nil
+}
+
+// QueryBatchReceipt queries the electronic receipt of the transfer batch,
+// which is downloaded with DownloadReceipt once FINISHED.
+func (c *V3Client) QueryBatchReceipt(outBatchNo string) (*TransferReceipt, error) {
+	rsp := &TransferReceipt{}
+	if err := c.doRequest(http.MethodGet, "/v3/transfer/bill-receipt/"+url.PathEscape(outBatchNo), nil, rsp); err != nil {
+		return nil, err
+	}
+	return rsp, nil
+}
+
+// ApplyDetailReceipt applies for the electronic receipt of the successful
+// transfer detail.
+func (c *V3Client) ApplyDetailReceipt(outBatchNo, outDetailNo string) (*TransferReceipt, error) {
+	rsp := &TransferReceipt{}
+	req := map[string]string{
+		"accept_type":   "BATCH_TRANSFER",
+		"out_batch_no":  outBatchNo,
+		"out_detail_no": outDetailNo,
+	}
+	if err := c.doRequest(http.MethodPost, "/v3/transfer-detail/electronic-receipts", req, rsp); err != nil {
+		return nil, err
+	}
+	return rsp, nil
+}
+
+// QueryDetailReceipt queries the electronic receipt of the transfer detail.
+func (c *V3Client) QueryDetailReceipt(outBatchNo, outDetailNo string) (*TransferReceipt, error) {
+	query := url.Values{}
+	query.Set("accept_type", "BATCH_TRANSFER")
+	query.Set("out_batch_no", outBatchNo)
+	query.Set("out_detail_no", outDetailNo)
+
+	rsp := &TransferReceipt{}
+	if err := c.doRequest(http.MethodGet, "/v3/transfer-detail/electronic-receipts?"+query.Encode(), nil, rsp); err != nil {
+		return nil, err
+	}
+	return rsp, nil
+}
+
+// DownloadReceipt streams the PDF of receipt into w, verifying its hash.
+func (c *V3Client) DownloadReceipt(receipt *TransferReceipt, w io.Writer) error {
+	if receipt.SignatureStatus != "FINISHED" || receipt.DownloadURL == "" {
+		return errors.New("receipt not generated yet")
+	}
+
+	return c.DownloadBill(&BillInfo{
+		HashType:    receipt.HashType,
+		HashValue:   receipt.HashValue,
+		DownloadURL: receipt.DownloadURL,
+	}, w)
+}
+
+type transferBatchReq struct {
+	AppID       string `json:"appid"`        // 商户appid
+	TotalAmount int    `json:"total_amount"` // 转账总金额
+	TotalNum    int    `json:"total_num"`    // 转账总笔数
+	TransferBatchOptions
+}
+
+// CreateTransferBatchRsp is the response returned by /v3/transfer/batches.
+type CreateTransferBatchRsp struct {
+	OutBatchNo string `json:"out_batch_no"` // 商家批次单号
+	BatchID    string `json:"batch_id"`     // 微信批次单号
+	CreateTime string `json:"create_time"`  // 批次创建时间
+}
+
+// QueryTransferBatchRsp is the transfer batch with its details.
+type QueryTransferBatchRsp struct {
+	TransferBatch      TransferBatch         `json:"transfer_batch"`                 // 转账批次单
+	TransferDetailList []TransferDetailBrief `json:"transfer_detail_list,omitempty"` // 转账明细单列表
+}
+
+// TransferBatch is the transfer batch in fen.
+type TransferBatch struct {
+	MchID           string              `json:"mchid"`             // 商户号
+	OutBatchNo      string              `json:"out_batch_no"`      // 商家批次单号
+	BatchID         string              `json:"batch_id"`          // 微信批次单号
+	AppID           string              `json:"appid"`             // 商户appid
+	BatchStatus     TransferBatchStatus `json:"batch_status"`      // 批次状态
+	BatchType       string              `json:"batch_type"`        // 批次类型
+	BatchName       string              `json:"batch_name"`        // 批次名称
+	BatchRemark     string              `json:"batch_remark"`      // 批次备注
+	CloseReason     string              `json:"close_reason"`      // 批次关闭原因
+	TotalAmount     int                 `json:"total_amount"`      // 转账总金额
+	TotalNum        int                 `json:"total_num"`         // 转账总笔数
+	CreateTime      string              `json:"create_time"`       // 批次创建时间
+	UpdateTime      string              `json:"update_time"`       // 批次更新时间
+	SuccessAmount   int                 `json:"success_amount"`    // 转账成功金额
+	SuccessNum      int                 `json:"success_num"`       // 转账成功笔数
+	FailAmount      int                 `json:"fail_amount"`       // 转账失败金额
+	FailNum         int                 `json:"fail_num"`          // 转账失败笔数
+	TransferSceneID string              `json:"transfer_scene_id"` // 转账场景ID
+}
+
+// TransferDetailBrief is the status of a detail in the transfer batch.
+type TransferDetailBrief struct {
+	DetailID     string               `json:"detail_id"`     // 微信明细单号
+	OutDetailNo  string               `json:"out_detail_no"` // 商家明细单号
+	DetailStatus TransferDetailStatus `json:"detail_status"` // 明细状态
+}
+
+// TransferDetailRsp is the transfer detail in fen.
+type TransferDetailRsp struct {
+	MchID          string               `json:"mchid"`                         // 商户号
+	OutBatchNo     string               `json:"out_batch_no"`                  // 商家批次单号
+	BatchID        string               `json:"batch_id"`                      // 微信批次单号
+	AppID          string               `json:"appid"`                         // 商户appid
+	OutDetailNo    string               `json:"out_detail_no"`                 // 商家明细单号
+	DetailID       string               `json:"detail_id"`                     // 微信明细单号
+	DetailStatus   TransferDetailStatus `json:"detail_status"`                 // 明细状态
+	TransferAmount int                  `json:"transfer_amount"`               // 转账金额
+	TransferRemark string               `json:"transfer_remark"`               // 转账备注
+	FailReason     string               `json:"fail_reason"`                   // 明细失败原因
+	OpenID         string               `json:"openid"`                        // 收款用户openid
+	UserName       string               `json:"user_name" wechatpay:"encrypt"` // 收款用户姓名
+	InitiateTime   string               `json:"initiate_time"`                 // 转账发起时间
+	UpdateTime     string               `json:"update_time"`                   // 明细更新时间
+}
+
+// TransferReceipt is the electronic receipt of transfer batch or detail,
+// available to download once SignatureStatus is FINISHED.
+type TransferReceipt struct {
+	AcceptType      string `json:"accept_type,omitempty"`   // 受理类型
+	OutBatchNo      string `json:"out_batch_no"`            // 商家批次单号
+	OutDetailNo     string `json:"out_detail_no,omitempty"` // 商家明细单号
+	SignatureNo     string `json:"signature_no"`            // 电子回单申请单号
+	SignatureStatus string `json:"signature_status"`        // 电子回单状态，ACCEPTED或FINISHED
+	HashType        string `json:"hash_type"`               // 电子回单文件的hash方法
+	HashValue       string `json:"hash_value"`              // 电子回单文件的hash值
+	DownloadURL     string `json:"download_url"`            // 电子回单文件的下载地址
+	CreateTime      string `json:"create_time"`             // 创建时间
+	UpdateTime      string `json:"update_time"`             // 更新时间
+}
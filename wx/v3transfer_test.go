@@ -0,0 +1,75 @@
+package wx
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCreateTransferBatch(t *testing.T) {
+	env := newV3TestEnv(t)
+	var received transferBatchReq
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var rsp string
+		switch r.URL.Path {
+		case "/v3/transfer/batches":
+			body, _ := ioutil.ReadAll(r.Body)
+			json.Unmarshal(body, &received)
+			rsp = `{"out_batch_no":"plfk2020042013","batch_id":"1030000071100999991182020050700019480001","create_time":"2015-05-20T13:29:35.120+08:00"}`
+		case "/v3/transfer/batches/out-batch-no/plfk2020042013":
+			if r.URL.RawQuery != "detail_status=FAIL&limit=100&need_query_detail=true" {
+				t.Errorf("returned: %s", r.URL.RawQuery)
+			}
+			rsp = `{"transfer_batch":{"out_batch_no":"plfk2020042013","batch_status":"FINISHED","total_num":2,"fail_num":1},` +
+				`"transfer_detail_list":[{"detail_id":"1040000071100999991182020050700019500100","out_detail_no":"x23zy545Bd5436","detail_status":"FAIL"}]}`
+		}
+		env.sign(t, w.Header(), rsp)
+		fmt.Fprint(w, rsp)
+	}))
+	defer srv.Close()
+
+	c := env.client(srv)
+	opts := TransferBatchOptions{
+		OutBatchNo:  "plfk2020042013",
+		BatchName:   "2019年1月深圳分部报销单",
+		BatchRemark: "2019年1月深圳分部报销单",
+		TransferDetailList: []TransferDetail{
+			{OutDetailNo: "x23zy545Bd5436", TransferAmount: 200000, TransferRemark: "2020年4月报销", OpenID: "o-MYE42l80oelYMDE34nYD456Xoy", UserName: "张三"},
+			{OutDetailNo: "x23zy545Bd5437", TransferAmount: 100, TransferRemark: "2020年4月报销", OpenID: "o-MYE42l80oelYMDE34nYD456Xoz"},
+		},
+	}
+	rsp, err := c.CreateTransferBatch(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rsp.BatchID != "1030000071100999991182020050700019480001" {
+		t.Errorf("returned: %s", rsp.BatchID)
+	}
+	if received.TotalAmount != 200100 || received.TotalNum != 2 || received.AppID != "wxd678efh567hg6787" {
+		t.Errorf("returned: %#v", received)
+	}
+	if name := received.TransferDetailList[0].UserName; name == "" || name == "张三" {
+		t.Errorf("expected user_name to be encrypted, returned: %s", name)
+	}
+
+	batch, err := c.QueryTransferBatchByOutBatchNo("plfk2020042013", QueryTransferBatchOptions{NeedQueryDetail: true, Limit: 100, DetailStatus: "FAIL"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if batch.TransferBatch.BatchStatus != TransferBatchFinished || batch.TransferDetailList[0].DetailStatus != TransferDetailFail {
+		t.Errorf("returned: %#v", batch)
+	}
+
+	opts.TransferDetailList[0].UserName = ""
+	if _, err = c.CreateTransferBatch(opts); err == nil {
+		t.Error("expected failure without user_name for 2000 yuan")
+	}
+
+	opts.TransferDetailList = make([]TransferDetail, maxTransferDetails+1)
+	if _, err = c.CreateTransferBatch(opts); err == nil {
+		t.Error("expected failure with more than 1000 details")
+	}
+}